@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Agent mode: a systemd timer runs --check on a schedule, keeping the
+// latest JSON result and the history. Everything installed is listed in
+// the agent manifest so --uninstall-agent removes exactly that.
+var (
+	agentBinPath      = "/usr/local/bin/xc-baseline-go"
+	agentConfigDir    = "/etc/xc-baseline"
+	agentManifestPath = "/etc/xc-baseline/agent.json"
+	agentUnitDir      = "/etc/systemd/system"
+	agentResultPath   = "/var/lib/xc-baseline/result.json"
+)
+
+const (
+	agentUnit  = "xc-baseline"
+	agentTimer = agentUnit + ".timer"
+)
+
+type AgentOptions struct {
+	Schedule string
+	Jitter   time.Duration
+	Profile  string
+	Waivers  string
+	OVAL     string
+	PromFile string
+	Upload   string
+	SignKey  string
+}
+
+type agentManifest struct {
+	Installed   time.Time `json:"installed"`
+	Schedule    string    `json:"schedule"`
+	Files       []string  `json:"files"`
+	CreatedDirs []string  `json:"created_dirs"`
+}
+
+func installAgent(ctx context.Context, opts AgentOptions) ([]string, error) {
+	if os.Geteuid() != 0 {
+		return nil, errors.New("安装定时检查需要root权限")
+	}
+	if _, code := runCommand(ctx, "systemctl", "--version"); code != 0 {
+		return nil, errors.New("未检测到systemd，无法安装定时检查")
+	}
+	if opts.Jitter < 0 {
+		return nil, errors.New("--jitter 不能为负数")
+	}
+	if opts.PromFile != "" && !filepath.IsAbs(opts.PromFile) {
+		return nil, errors.New("--prom-textfile 须为绝对路径")
+	}
+	if out, code := runCommand(ctx, "systemd-analyze", "calendar", opts.Schedule); code > 0 {
+		return nil, fmt.Errorf("--schedule 不是有效的 systemd OnCalendar 表达式: %s", out)
+	}
+	// A broken profile or waiver file would only show up in the journal.
+	if opts.Profile != "" {
+		if _, err := loadProfile(opts.Profile); err != nil {
+			return nil, fmt.Errorf("基线配置加载失败: %v", err)
+		}
+	}
+	if opts.Waivers != "" {
+		if _, err := loadWaivers(opts.Waivers); err != nil {
+			return nil, fmt.Errorf("豁免文件加载失败: %v", err)
+		}
+	}
+	if opts.SignKey != "" {
+		if _, err := loadSignKey(opts.SignKey); err != nil {
+			return nil, fmt.Errorf("签名密钥加载失败: %v", err)
+		}
+	}
+	manifest := agentManifest{Files: []string{}, CreatedDirs: []string{}}
+	// Re-installing keeps track of what an earlier install created.
+	_ = readJSONFile(agentManifestPath, &manifest)
+	manifest.Installed = time.Now()
+	manifest.Schedule = opts.Schedule
+
+	track := func(path string) {
+		if !containsString(manifest.Files, path) {
+			manifest.Files = append(manifest.Files, path)
+		}
+	}
+	mkdir := func(dir string) error {
+		created, err := mkdirRecorded(dir)
+		for _, d := range created {
+			if !containsString(manifest.CreatedDirs, d) {
+				manifest.CreatedDirs = append(manifest.CreatedDirs, d)
+			}
+		}
+		return err
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	if err := mkdir(filepath.Dir(agentBinPath)); err != nil {
+		return nil, err
+	}
+	if err := copyFile(self, agentBinPath, 0755); err != nil {
+		return nil, err
+	}
+	track(agentBinPath)
+
+	if err := mkdir(agentConfigDir); err != nil {
+		return nil, err
+	}
+	args := []string{agentBinPath, "--check", "--format", "json", "--output", agentResultPath, "--save-history"}
+	for _, cfg := range []struct {
+		flag, src, name string
+		mode            os.FileMode
+	}{
+		{"--profile", opts.Profile, "profile", 0644},
+		{"--waivers", opts.Waivers, "waivers", 0644},
+		{"--sign-key", opts.SignKey, "sign", 0600},
+	} {
+		if cfg.src == "" {
+			continue
+		}
+		dst := filepath.Join(agentConfigDir, cfg.name+strings.ToLower(filepath.Ext(cfg.src)))
+		if err := copyFile(cfg.src, dst, cfg.mode); err != nil {
+			return nil, err
+		}
+		track(dst)
+		args = append(args, cfg.flag, dst)
+	}
+	ovalFiles := []string{}
+	for i, src := range splitList(opts.OVAL) {
+		dst := filepath.Join(agentConfigDir, fmt.Sprintf("oval-%d.xml", i+1))
+		if err := copyFile(src, dst, 0644); err != nil {
+			return nil, err
+		}
+		track(dst)
+		ovalFiles = append(ovalFiles, dst)
+	}
+	if len(ovalFiles) > 0 {
+		args = append(args, "--oval", strings.Join(ovalFiles, ","))
+	}
+	if opts.PromFile != "" {
+		args = append(args, "--prom-textfile", opts.PromFile)
+	}
+	if opts.Upload != "" {
+		args = append(args, "--upload", opts.Upload)
+	}
+
+	if err := mkdir(filepath.Dir(agentResultPath)); err != nil {
+		return nil, err
+	}
+	// "%" starts a specifier in unit files, e.g. in an upload URL.
+	service := fmt.Sprintf(`[Unit]
+Description=信创基线定时检查
+After=network-online.target
+
+[Service]
+Type=oneshot
+ExecStart=%s
+# 1: 存在失败项, 2: 需人工确认，均为正常完成的检查
+SuccessExitStatus=1 2
+Nice=10
+IOSchedulingClass=idle
+`, strings.ReplaceAll(strings.Join(args, " "), "%", "%%"))
+	timer := fmt.Sprintf(`[Unit]
+Description=信创基线定时检查
+
+[Timer]
+OnCalendar=%s
+RandomizedDelaySec=%d
+Persistent=true
+
+[Install]
+WantedBy=timers.target
+`, opts.Schedule, int(opts.Jitter.Seconds()))
+	for name, content := range map[string]string{agentUnit + ".service": service, agentTimer: timer} {
+		path := filepath.Join(agentUnitDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return nil, err
+		}
+		track(path)
+	}
+	if err := writeJSONFile(agentManifestPath, manifest); err != nil {
+		return nil, err
+	}
+	for _, argv := range [][]string{{"daemon-reload"}, {"enable", "--now", agentTimer}} {
+		if out, code := runCommand(ctx, "systemctl", argv...); code != 0 {
+			return manifest.Files, fmt.Errorf("systemctl %s 失败: %s", strings.Join(argv, " "), out)
+		}
+	}
+	return manifest.Files, nil
+}
+
+// uninstallAgent stops the timer and removes the files and directories
+// listed in the manifest. Results and history under /var/lib are kept.
+func uninstallAgent(ctx context.Context) ([]string, error) {
+	if os.Geteuid() != 0 {
+		return nil, errors.New("卸载定时检查需要root权限")
+	}
+	var manifest agentManifest
+	if err := readJSONFile(agentManifestPath, &manifest); err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New("未安装定时检查（未找到 " + agentManifestPath + "）")
+		}
+		return nil, err
+	}
+	_, _ = runCommand(ctx, "systemctl", "disable", "--now", agentTimer)
+	removed := []string{}
+	for _, path := range manifest.Files {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	if err := os.Remove(agentManifestPath); err != nil && !os.IsNotExist(err) {
+		return removed, err
+	}
+	removed = append(removed, agentManifestPath)
+	// Deepest first; directories that gained other content stay.
+	for i := len(manifest.CreatedDirs) - 1; i >= 0; i-- {
+		if err := os.Remove(manifest.CreatedDirs[i]); err == nil {
+			removed = append(removed, manifest.CreatedDirs[i])
+		}
+	}
+	_, _ = runCommand(ctx, "systemctl", "daemon-reload")
+	return removed, nil
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	// Write beside the target and rename, so a running binary is replaced
+	// rather than truncated.
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chmod(tmp, mode); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ansibleRole is one generated role: tasks plus optional handlers and
+// files (name to content) for the role's files/ directory.
+type ansibleRole struct {
+	tasks    scriptWriter
+	handlers scriptWriter
+	files    map[string]string
+}
+
+func yamlString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+func yamlList(values []string) string {
+	quoted := []string{}
+	for _, v := range values {
+		quoted = append(quoted, yamlString(v))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// jinjaList renders values as a list literal usable inside "{{ }}".
+func jinjaList(values []string) string {
+	quoted := []string{}
+	for _, v := range values {
+		quoted = append(quoted, "'"+v+"'")
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// emitAnsible writes a playbook directory with one role per failed item of
+// payload. Values come from the same helpers the --apply fixes use.
+func emitAnsible(profile *Profile, payload Output, dir string) error {
+	roles := []string{}
+	manual := []string{}
+	files := map[string]string{}
+	for _, item := range payload.Items {
+		if item.Status != "fail" {
+			continue
+		}
+		role := &ansibleRole{}
+		if !writeAnsibleRole(profile, role, item.ID) {
+			manual = append(manual, item.ID)
+			continue
+		}
+		name := "xc_" + item.ID
+		roles = append(roles, name)
+		files[filepath.Join("roles", name, "tasks", "main.yml")] = "---\n" + role.tasks.sb.String()
+		if role.handlers.sb.Len() > 0 {
+			files[filepath.Join("roles", name, "handlers", "main.yml")] = "---\n" + role.handlers.sb.String()
+		}
+		for file, content := range role.files {
+			files[filepath.Join("roles", name, "files", file)] = content
+		}
+	}
+
+	site := &scriptWriter{}
+	site.line("---")
+	site.line("# 信创基线修复 playbook：由 xc-baseline-go --emit-ansible 生成，执行前请审阅。")
+	site.line("# 来源: %s", payload.OS)
+	site.line("# 基线配置: %s (%s)", payload.Profile.Name, payload.Profile.Hash)
+	if len(manual) > 0 {
+		site.line("# 需人工处理: %s", strings.Join(manual, ", "))
+	}
+	site.line("# 依赖集合: ansible.posix, community.general")
+	site.line("- name: 信创基线修复")
+	site.line("  hosts: all")
+	site.line("  become: true")
+	if len(roles) == 0 {
+		site.line("  roles: []")
+	} else {
+		site.line("  roles:")
+		for _, name := range roles {
+			site.line("    - %s", name)
+		}
+	}
+	files["site.yml"] = site.sb.String()
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		mode := os.FileMode(0644)
+		if strings.HasSuffix(name, ".sh") {
+			mode = 0755
+		}
+		if err := os.WriteFile(path, []byte(content), mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeAnsibleRole(profile *Profile, role *ansibleRole, id string) bool {
+	t := &role.tasks
+	switch id {
+	case "ftp_service":
+		t.line("- name: 收集服务状态")
+		t.line("  ansible.builtin.service_facts:")
+		t.line("- name: 停止并禁用FTP服务")
+		t.line("  ansible.builtin.systemd:")
+		t.line("    name: \"{{ item }}\"")
+		t.line("    state: stopped")
+		t.line("    enabled: false")
+		t.line("  loop: [vsftpd, proftpd, pure-ftpd, ftpd]")
+		t.line("  when: (item + '.service') in ansible_facts.services")
+	case "risky_ports":
+		writeAnsibleFirewall(profile, role)
+	case "usb_autoplay":
+		writeAnsibleDconf(role, usbAutoplaySettings())
+	case "lock_screen":
+		writeAnsibleDconf(role, lockScreenSettings(profile))
+	case "ipv6_disabled":
+		for _, key := range ipv6SysctlKeys() {
+			t.line("- name: %s", yamlString("设置 "+key+" = 1"))
+			t.line("  ansible.posix.sysctl:")
+			t.line("    name: %s", key)
+			t.line("    value: \"1\"")
+			t.line("    sysctl_file: %s", ipv6SysctlPath)
+			t.line("    reload: true")
+		}
+	case "password_policy":
+		writeAnsiblePassword(profile, role)
+	case "patch_updates":
+		t.line("- name: 安装系统更新（apt）")
+		t.line("  ansible.builtin.apt:")
+		t.line("    update_cache: true")
+		t.line("    upgrade: safe")
+		t.line("  when: ansible_pkg_mgr == 'apt'")
+		t.line("- name: 安装系统更新（dnf/yum）")
+		t.line("  ansible.builtin.package:")
+		t.line("    name: \"*\"")
+		t.line("    state: latest")
+		t.line("  when: ansible_pkg_mgr in ['dnf', 'yum']")
+	case "audit_rules":
+		t.line("- name: 安装审计服务")
+		t.line("  ansible.builtin.package:")
+		t.line("    name: \"{{ 'auditd' if ansible_os_family == 'Debian' else 'audit' }}\"")
+		t.line("    state: present")
+		t.line("- name: 启用auditd")
+		t.line("  ansible.builtin.systemd:")
+		t.line("    name: auditd")
+		t.line("    state: started")
+		t.line("    enabled: true")
+		t.line("- name: 写入审计规则")
+		t.line("  ansible.builtin.copy:")
+		t.line("    dest: /etc/audit/rules.d/xc-baseline.rules")
+		t.line("    content: %s", yamlString(strings.Join(remediationAuditRules(profile), "\n")+"\n"))
+		t.line("    mode: \"0640\"")
+		t.line("  notify: 加载审计规则")
+		role.handlers.line("- name: 加载审计规则")
+		role.handlers.line("  ansible.builtin.command: augenrules --load")
+	default:
+		return false
+	}
+	return true
+}
+
+func writeAnsibleDconf(role *ansibleRole, settings []dconfSetting) {
+	t := &role.tasks
+	for _, setting := range settings {
+		t.line("- name: %s", yamlString("dconf "+setting.lockPath()))
+		t.line("  community.general.ini_file:")
+		t.line("    path: %s", dconfConfigPath)
+		t.line("    section: %s", setting.Section)
+		t.line("    option: %s", setting.Key)
+		t.line("    value: %s", yamlString(setting.Value))
+		t.line("    no_extra_spaces: true")
+		t.line("    mode: \"0644\"")
+		t.line("  notify: dconf update")
+		t.line("- name: %s", yamlString("锁定 "+setting.lockPath()))
+		t.line("  ansible.builtin.lineinfile:")
+		t.line("    path: %s", dconfLockPath)
+		t.line("    line: %s", setting.lockPath())
+		t.line("    create: true")
+		t.line("    mode: \"0644\"")
+		t.line("  notify: dconf update")
+	}
+	role.handlers.line("- name: dconf update")
+	role.handlers.line("  ansible.builtin.command: dconf update")
+}
+
+// writeAnsibleFirewall blocks the profile's risky ports with ufw on UOS,
+// firewalld rich rules where firewalld runs and iptables otherwise.
+func writeAnsibleFirewall(profile *Profile, role *ansibleRole) {
+	t := &role.tasks
+	ports := []string{}
+	for _, port := range profile.RiskyPorts {
+		ports = append(ports, fmt.Sprintf("%d", port))
+	}
+	isUOS := "ansible_distribution | lower is search('uos|uniontech')"
+	hasFirewalld := "'firewalld.service' in ansible_facts.services"
+	t.line("- name: 收集服务状态")
+	t.line("  ansible.builtin.service_facts:")
+	t.line("- name: 拒绝高危端口入站（ufw）")
+	t.line("  community.general.ufw:")
+	t.line("    rule: deny")
+	t.line("    port: \"{{ item.0 }}\"")
+	t.line("    proto: \"{{ item.1 }}\"")
+	t.line("  loop: \"{{ %s | product(['tcp', 'udp']) | list }}\"", jinjaList(ports))
+	t.line("  when: %s", isUOS)
+	t.line("# 启用后默认拒绝入站连接，请确认业务端口已放行。")
+	t.line("- name: 启用ufw")
+	t.line("  community.general.ufw:")
+	t.line("    state: enabled")
+	t.line("  when: %s", isUOS)
+	t.line("- name: 高危端口拒绝规则（firewalld）")
+	t.line("  ansible.posix.firewalld:")
+	t.line("    rich_rule: \"{{ item }}\"")
+	t.line("    permanent: true")
+	t.line("    immediate: true")
+	t.line("    state: enabled")
+	t.line("  loop: %s", yamlList(firewalldRichRules(profile.RiskyPorts)))
+	t.line("  when: not (%s) and %s", isUOS, hasFirewalld)
+	t.line("# iptables 规则为运行时规则，需另行持久化（如 iptables-save）。")
+	t.line("- name: 高危端口丢弃规则（iptables）")
+	t.line("  ansible.builtin.iptables:")
+	t.line("    ip_version: \"{{ item.0 }}\"")
+	t.line("    chain: \"{{ item.1 }}\"")
+	t.line("    protocol: \"{{ item.2 }}\"")
+	t.line("    destination_port: \"{{ item.3 }}\"")
+	t.line("    jump: DROP")
+	t.line("  loop: \"{{ ['ipv4', 'ipv6'] | product(['INPUT', 'OUTPUT'], ['tcp', 'udp'], %s) | list }}\"", jinjaList(ports))
+	t.line("  when: not (%s) and not (%s)", isUOS, hasFirewalld)
+}
+
+func writeAnsiblePassword(profile *Profile, role *ansibleRole) {
+	t := &role.tasks
+	policy := profile.Password
+	maxKey, minKey, lenKey := loginDefsKeys("")
+	for _, kv := range loginDefsSettings(policy, maxKey, minKey, lenKey) {
+		t.line("- name: %s", yamlString("login.defs "+kv.Key))
+		t.line("  ansible.builtin.lineinfile:")
+		t.line("    path: /etc/login.defs")
+		t.line("    regexp: %s", yamlString(`^\s*`+kv.Key+`(\s|=|$)`))
+		t.line("    line: %s", yamlString(kv.Key+"   "+kv.Value))
+	}
+	for _, kv := range pwqualitySettings(policy) {
+		t.line("- name: %s", yamlString("pwquality.conf "+kv.Key))
+		t.line("  ansible.builtin.lineinfile:")
+		t.line("    path: /etc/security/pwquality.conf")
+		t.line("    regexp: %s", yamlString(`^\s*`+kv.Key+`(\s|=|$)`))
+		t.line("    line: %s", yamlString(kv.Key+" = "+kv.Value))
+		t.line("    create: true")
+		t.line("    mode: \"0644\"")
+	}
+	// Same layout rule as pamFilesByDistro: Debian-like uses common-*.
+	t.line("- name: 选择PAM配置文件")
+	t.line("  ansible.builtin.set_fact:")
+	t.line("    xc_pam_password: \"{{ '/etc/pam.d/common-password' if ansible_os_family == 'Debian' else '/etc/pam.d/system-auth' }}\"")
+	t.line("    xc_pam_auth: \"{{ '/etc/pam.d/common-auth' if ansible_os_family == 'Debian' else '/etc/pam.d/system-auth' }}\"")
+	t.line("- name: 密码复杂度（pam_pwquality）")
+	t.line("  ansible.builtin.lineinfile:")
+	t.line("    path: \"{{ xc_pam_password }}\"")
+	t.line("    regexp: %s", yamlString(`^\s*password\s.*pam_(pwquality|cracklib)\.so`))
+	t.line("    line: %s", yamlString(pwqualityPamLine(policy)))
+	t.line("    insertbefore: %s", yamlString(`^\s*password\s.*pam_unix\.so`))
+	if policy.Remember > 0 {
+		// Keep the distro's pam_unix control flags and hash; only set remember=N.
+		t.line("- name: 密码历史（更新 pam_unix remember）")
+		t.line("  ansible.builtin.replace:")
+		t.line("    path: \"{{ xc_pam_password }}\"")
+		t.line("    regexp: %s", yamlString(`^([ \t]*password[ \t].*pam_unix\.so.*[ \t])remember=\d+`))
+		t.line("    replace: %s", yamlString(fmt.Sprintf(`\g<1>remember=%d`, policy.Remember)))
+		t.line("- name: 密码历史（添加 pam_unix remember）")
+		t.line("  ansible.builtin.replace:")
+		t.line("    path: \"{{ xc_pam_password }}\"")
+		t.line("    regexp: %s", yamlString(`^([ \t]*password[ \t](?!.*[ \t]remember=).*pam_unix\.so.*?)[ \t]*$`))
+		t.line("    replace: %s", yamlString(fmt.Sprintf(`\g<1> remember=%d`, policy.Remember)))
+	}
+	for _, module := range []string{"pam_faillock", "pam_tally2"} {
+		t.line("- name: %s", yamlString("查找 "+module+" 模块"))
+		t.line("  ansible.builtin.find:")
+		t.line("    paths: %s", yamlList(pamModuleDirs))
+		t.line("    patterns: %s.so", module)
+		t.line("  register: xc_%s", module)
+	}
+	role.files = map[string]string{ansiblePamScript: ansiblePamPlaceScript()}
+	writeAnsiblePamLines(t, faillockPamLines(policy), "xc_pam_faillock.matched > 0")
+	writeAnsiblePamLines(t, tally2PamLines(policy), "xc_pam_faillock.matched == 0 and xc_pam_tally2.matched > 0")
+}
+
+const ansiblePamScript = "xc_pam_place.sh"
+
+// ansiblePamPlaceScript runs the fix script's pam_place on the managed host:
+// lineinfile can only append or anchor, not widen a [success=N] jump that
+// skips over the inserted line. The backup sits beside the file, as
+// lineinfile's backup option does.
+func ansiblePamPlaceScript() string {
+	return `#!/bin/sh
+# 由 xc-baseline-go --emit-ansible 生成。用法: xc_pam_place.sh FILE NEEDLE LINE TYPE ANCHOR WHERE
+set -eu
+
+replace_if_changed() {
+  if [ -f "$1" ] && cmp -s "$1" "$2"; then
+    rm -f "$2"
+    return 0
+  fi
+  [ -e "$1" ] && cp -a "$1" "$1.$(date +%Y%m%d%H%M%S)~"
+  cat "$2" > "$1"
+  rm -f "$2"
+  echo "已更新: $1"
+}
+
+` + pamPlaceShell + `
+pam_place "$@"
+`
+}
+
+// writeAnsiblePamLines places the lines with the role's pam_place script,
+// so they land where the --apply fix puts them.
+func writeAnsiblePamLines(t *scriptWriter, lines []pamLine, when string) {
+	for _, line := range lines {
+		t.line("- name: %s", yamlString("登录失败锁定: "+line.Line))
+		t.line("  ansible.builtin.script:")
+		t.line("    cmd: %s", yamlString(ansiblePamScript+" {{ xc_pam_auth | quote }} "+shellArgs(pamPlaceArgs(line))))
+		t.line("  register: xc_pam_place")
+		t.line("  changed_when: \"'已更新' in xc_pam_place.stdout\"")
+		t.line("  when: %s", when)
+	}
+}
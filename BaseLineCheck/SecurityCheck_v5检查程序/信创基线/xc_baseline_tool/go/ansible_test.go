@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type ansibleTask struct {
+	Name   string            `json:"name"`
+	When   string            `json:"when"`
+	Script map[string]string `json:"ansible.builtin.script"`
+}
+
+func passwordRoleTasks(t *testing.T, profile *Profile) (*ansibleRole, []ansibleTask) {
+	t.Helper()
+	role := &ansibleRole{}
+	if !writeAnsibleRole(profile, role, "password_policy") {
+		t.Fatal("password_policy role not written")
+	}
+	data, err := yamlToJSON([]byte(role.tasks.sb.String()))
+	if err != nil {
+		t.Fatalf("tasks are not valid YAML: %v", err)
+	}
+	var tasks []ansibleTask
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		t.Fatal(err)
+	}
+	return role, tasks
+}
+
+// runPamTask runs a script task's cmd the way the script module does: the
+// role's files/ script with the rest of cmd as shell arguments.
+func runPamTask(dir, cmd, path string) ([]byte, error) {
+	cmd = strings.Replace(cmd, "{{ xc_pam_auth | quote }}", shellQuote(path), 1)
+	args := strings.TrimPrefix(cmd, ansiblePamScript)
+	return exec.Command("sh", "-c", "sh "+shellQuote(filepath.Join(dir, ansiblePamScript))+args).CombinedOutput()
+}
+
+// TestAnsiblePamPlacement runs the role's faillock tasks through its
+// pam_place script, as the managed host would, and expects the same
+// stack as the --apply fix.
+func TestAnsiblePamPlacement(t *testing.T) {
+	for _, tool := range []string{"sh", "awk", "cmp"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not available", tool)
+		}
+	}
+	profile := defaultProfile()
+	profile.Password = testPolicy
+	role, tasks := passwordRoleTasks(t, profile)
+	dir := t.TempDir()
+	script := filepath.Join(dir, ansiblePamScript)
+	if err := os.WriteFile(script, []byte(role.files[ansiblePamScript]), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cmds := []string{}
+	for _, task := range tasks {
+		if task.Script != nil && strings.Contains(task.When, "xc_pam_faillock.matched > 0") {
+			cmds = append(cmds, task.Script["cmd"])
+		}
+	}
+	if len(cmds) != len(faillockPamLines(testPolicy)) {
+		t.Fatalf("found %d faillock script tasks", len(cmds))
+	}
+
+	stacks := map[string][]string{"debian": debianCommonAuth, "rhel": rhelSystemAuth}
+	for name, stack := range stacks {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "common-auth")
+			if err := os.WriteFile(path, []byte(strings.Join(stack, "\n")+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			run := func() {
+				for _, cmd := range cmds {
+					if out, err := runPamTask(dir, cmd, path); err != nil {
+						t.Fatalf("%s: %v\n%s", cmd, err, out)
+					}
+				}
+			}
+			run()
+			data, _ := os.ReadFile(path)
+			got := splitFileLines(string(data))
+			want, err := applyPamLines(stack, faillockPamLines(testPolicy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+			if err := checkAuthStack(got); err != nil {
+				t.Errorf("checkAuthStack: %v", err)
+			}
+			run()
+			again, _ := os.ReadFile(path)
+			if string(again) != string(data) {
+				t.Errorf("second run changed the file:\n%s", again)
+			}
+		})
+	}
+
+	t.Run("refuses a stack it would break", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "common-auth")
+		broken := "auth required pam_unix.so\nauth required pam_deny.so\n"
+		if err := os.WriteFile(path, []byte(broken), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := runPamTask(dir, cmds[1], path); err == nil {
+			t.Error("authfail placed after a required pam_unix")
+		}
+		if data, _ := os.ReadFile(path); string(data) != broken {
+			t.Errorf("file changed:\n%s", data)
+		}
+	})
+}
+
+func TestAnsiblePasswordKeepsPamUnixFlags(t *testing.T) {
+	profile := defaultProfile()
+	profile.Password = testPolicy
+	role, _ := passwordRoleTasks(t, profile)
+	tasks := role.tasks.sb.String()
+	if strings.Contains(tasks, unixPamLine(testPolicy)) {
+		t.Error("role replaces the whole pam_unix password line")
+	}
+	if !strings.Contains(tasks, "ansible.builtin.replace:") || !strings.Contains(tasks, `remember=5`) {
+		t.Errorf("role does not set remember=5:\n%s", tasks)
+	}
+}
@@ -11,8 +11,14 @@ ROOT_DIR=$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)
 GOOS=${GOOS:-linux}
 ARCHS=("amd64" "arm64")
 
+# XC_SIGN_KEY=$(cat site.key) 将签名私钥编入程序，--check --format json --output 自动生成 .sig
+LDFLAGS="-s -w"
+if [ -n "${XC_SIGN_KEY:-}" ]; then
+  LDFLAGS="$LDFLAGS -X main.embeddedSignKey=$XC_SIGN_KEY"
+fi
+
 for arch in "${ARCHS[@]}"; do
   CGO_ENABLED=0 GOOS=$GOOS GOARCH=$arch \
-    go build -ldflags "-s -w" -o "$ROOT_DIR/xc-baseline-go-$arch" "$ROOT_DIR/main.go"
+    go build -ldflags "$LDFLAGS" -o "$ROOT_DIR/xc-baseline-go-$arch" "$ROOT_DIR"
   printf "已生成静态单文件: %s\n" "$ROOT_DIR/xc-baseline-go-$arch"
 done
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+func init() {
+	// Built-in baseline catalog, in display order.
+	Register(funcChecker{meta: CheckMeta{
+		ID:       "ftp_service",
+		Name:     "FTP服务禁用",
+		Desc:     "检查是否存在FTP服务运行，基线要求禁用。",
+		Expected: "FTP服务未运行且已禁用",
+		Category: "service",
+		Severity: "high",
+		Live:     true,
+	}, check: checkFTPService, fix: applyFTPService})
+	registerFunc(CheckMeta{
+		ID:       "nic_info",
+		Name:     "网卡信息检查",
+		Desc:     "展示当前网卡与IP信息。",
+		Expected: "仅展示信息",
+		Category: "network",
+		Severity: "low",
+		Live:     true,
+	}, checkNICInfo)
+	Register(funcChecker{meta: CheckMeta{
+		ID:        "risky_ports",
+		Name:      "高危端口状态检测",
+		Desc:      "检测高危端口（默认22/23/135/137/138/139/445/455/3389/4899）监听状态。",
+		Expected:  "无高危端口监听",
+		Category:  "network",
+		Severity:  "high",
+		NeedsRoot: true,
+		Live:      true,
+	}, check: checkRiskyPorts, fix: applyRiskyPorts, expected: func(rc *RunContext) string {
+		return "无高危端口监听（" + rc.Profile.riskyPortsLabel() + "）"
+	}})
+	Register(funcChecker{meta: CheckMeta{
+		ID:       "usb_autoplay",
+		Name:     "U盘自动播放",
+		Desc:     "检查桌面环境自动挂载/自动打开策略。",
+		Expected: "自动挂载/自动打开关闭",
+		Category: "device",
+		Severity: "medium",
+	}, check: checkUSBAutoplay, fix: applyUSBAutoplay})
+	Register(funcChecker{meta: CheckMeta{
+		ID:       "ipv6_disabled",
+		Name:     "IPv6禁用状态",
+		Desc:     "检查IPv6禁用是否生效。",
+		Expected: "IPv6已禁用",
+		Category: "network",
+		Severity: "low",
+		Live:     true,
+	}, check: checkIPv6Disabled, fix: applyIPv6Disabled})
+	registerFunc(CheckMeta{
+		ID:       "patch_updates",
+		Name:     "高危漏洞修复",
+		Desc:     "检测系统更新状态（离线环境基于本地缓存判断）。",
+		Expected: "无待更新补丁",
+		Category: "system",
+		Severity: "high",
+		Live:     true,
+		// Repository metadata refreshes are slow on unreachable mirrors.
+		Timeout: 3 * time.Minute,
+	}, checkPatchUpdates)
+	Register(funcChecker{meta: CheckMeta{
+		ID:       "password_policy",
+		Name:     "密码策略",
+		Desc:     "检查密码最小长度/复杂度/有效期策略。",
+		Expected: "最小长度>=10，最短1天，最长90天，复杂度>=4类，失败锁定",
+		Category: "account",
+		Severity: "high",
+	}, check: checkPasswordPolicy, fix: applyPasswordPolicy, expected: func(rc *RunContext) string {
+		pw := rc.Profile.Password
+		return fmt.Sprintf("最小长度>=%d，最短%d天，最长%d天，复杂度>=%d类，历史%d次，失败锁定", pw.MinLen, pw.MinDays, pw.MaxDays, pw.MinClass, pw.Remember)
+	}})
+	Register(funcChecker{meta: CheckMeta{
+		ID:       "lock_screen",
+		Name:     "锁屏策略",
+		Desc:     "检查锁屏开启与自动锁定时间。",
+		Expected: "锁屏启用，空闲15分钟内锁定",
+		Category: "account",
+		Severity: "medium",
+	}, check: checkLockScreen, fix: applyLockScreen, expected: func(rc *RunContext) string {
+		return fmt.Sprintf("锁屏启用，空闲%d秒内锁定", rc.Profile.LockScreen.IdleDelay)
+	}})
+	Register(funcChecker{meta: CheckMeta{
+		ID:        "audit_rules",
+		Name:      "日志审计规则",
+		Desc:      "检查审计服务与规则配置。",
+		Expected:  "auditd运行且已加载规则",
+		Category:  "audit",
+		Severity:  "medium",
+		NeedsRoot: true,
+	}, check: checkAuditRules, expected: func(rc *RunContext) string {
+		if len(rc.Profile.AuditRules) == 0 {
+			return "auditd运行且已加载规则"
+		}
+		return fmt.Sprintf("auditd运行且包含%d条必需规则", len(rc.Profile.AuditRules))
+	}})
+}
@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestUpsertKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"empty file", "", "[org/gnome/desktop/screensaver]\nlock-delay=uint32 0\n"},
+		{"section missing",
+			"[org/gnome/desktop/session]\nidle-delay=uint32 300\n",
+			"[org/gnome/desktop/session]\nidle-delay=uint32 300\n\n[org/gnome/desktop/screensaver]\nlock-delay=uint32 0\n"},
+		{"replace in section",
+			"[org/gnome/desktop/screensaver]\nlock-enabled=true\nlock-delay = uint32 60\n",
+			"[org/gnome/desktop/screensaver]\nlock-enabled=true\nlock-delay=uint32 0\n"},
+		{"same key in another section",
+			"[org/ukui/screensaver]\nlock-delay=uint32 60\n\n[org/gnome/desktop/screensaver]\nlock-enabled=true\n\n[org/gnome/desktop/session]\nidle-delay=uint32 300\n",
+			"[org/ukui/screensaver]\nlock-delay=uint32 60\n\n[org/gnome/desktop/screensaver]\nlock-enabled=true\nlock-delay=uint32 0\n\n[org/gnome/desktop/session]\nidle-delay=uint32 300\n"},
+		{"key only in another section",
+			"[org/gnome/desktop/screensaver]\n\n[org/ukui/screensaver]\nlock-delay=uint32 60\n",
+			"[org/gnome/desktop/screensaver]\nlock-delay=uint32 0\n\n[org/ukui/screensaver]\nlock-delay=uint32 60\n"},
+		{"duplicates dropped",
+			"[org/gnome/desktop/screensaver]\nlock-delay=uint32 60\nlock-enabled=true\nlock-delay=uint32 30\n",
+			"[org/gnome/desktop/screensaver]\nlock-delay=uint32 0\nlock-enabled=true\n"},
+		{"no trailing newline",
+			"[org/gnome/desktop/screensaver]\nlock-enabled=true",
+			"[org/gnome/desktop/screensaver]\nlock-enabled=true\nlock-delay=uint32 0\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := upsertKey(tt.content, dconfScreensaver, "lock-delay", "uint32 0")
+			if got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+			if again := upsertKey(got, dconfScreensaver, "lock-delay", "uint32 0"); again != got {
+				t.Errorf("second upsert changed the file:\n%s", again)
+			}
+		})
+	}
+}
+
+func TestKeyfileValue(t *testing.T) {
+	content := "[org/ukui/screensaver]\nlock-enabled=true\n\n[org/gnome/desktop/screensaver]\nlock-enabled=false\nlock-delay = uint32 60\nlock-delay=uint32 0\n"
+	tests := []struct {
+		section, key string
+		value        string
+		line         int
+	}{
+		{dconfScreensaver, "lock-enabled", "false", 5},
+		{"org/ukui/screensaver", "lock-enabled", "true", 2},
+		{dconfScreensaver, "lock-delay", "uint32 0", 7},
+		{dconfSession, "idle-delay", "", 0},
+		{"org/ukui/screensaver", "lock-delay", "", 0},
+	}
+	for _, tt := range tests {
+		value, line := keyfileValue(content, tt.section, tt.key)
+		if value != tt.value || line != tt.line {
+			t.Errorf("keyfileValue(%s, %s) = %q, %d, want %q, %d", tt.section, tt.key, value, line, tt.value, tt.line)
+		}
+	}
+}
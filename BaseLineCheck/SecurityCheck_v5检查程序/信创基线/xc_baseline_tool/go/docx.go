@@ -0,0 +1,310 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// The DOCX report (检查报告) is a minimal WordprocessingML package written
+// with archive/zip: a cover page, a summary with the score and an overview
+// table, then one section per item.
+
+const (
+	docxPageWidth  = 11906 // A4 in twentieths of a point
+	docxPageHeight = 16838
+	docxMargin     = 1440
+	docxTextWidth  = docxPageWidth - 2*docxMargin
+	docxLabelWidth = 1800
+)
+
+var docxStatusColors = map[string]string{
+	"pass":    "2E7D32",
+	"fail":    "C62828",
+	"waived":  "E08A00",
+	"timeout": "E08A00",
+	"error":   "E08A00",
+	"manual":  "1565C0",
+}
+
+type docxRun struct {
+	text  string
+	bold  bool
+	color string
+}
+
+type docxBody struct {
+	sb strings.Builder
+}
+
+func docxEscape(text string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(text))
+	return sb.String()
+}
+
+func docxRuns(runs ...docxRun) string {
+	var sb strings.Builder
+	for _, r := range runs {
+		sb.WriteString("<w:r>")
+		if r.bold || r.color != "" {
+			sb.WriteString("<w:rPr>")
+			if r.bold {
+				sb.WriteString("<w:b/>")
+			}
+			if r.color != "" {
+				sb.WriteString(`<w:color w:val="` + r.color + `"/>`)
+			}
+			sb.WriteString("</w:rPr>")
+		}
+		for i, line := range strings.Split(r.text, "\n") {
+			if i > 0 {
+				sb.WriteString("<w:br/>")
+			}
+			sb.WriteString(`<w:t xml:space="preserve">` + docxEscape(line) + "</w:t>")
+		}
+		sb.WriteString("</w:r>")
+	}
+	return sb.String()
+}
+
+func (d *docxBody) para(style string, runs ...docxRun) {
+	d.sb.WriteString("<w:p>")
+	if style != "" {
+		d.sb.WriteString(`<w:pPr><w:pStyle w:val="` + style + `"/></w:pPr>`)
+	}
+	d.sb.WriteString(docxRuns(runs...))
+	d.sb.WriteString("</w:p>")
+}
+
+func (d *docxBody) text(style, text string) {
+	d.para(style, docxText(text))
+}
+
+func (d *docxBody) pageBreak() {
+	d.sb.WriteString(`<w:p><w:r><w:br w:type="page"/></w:r></w:p>`)
+}
+
+// table writes a bordered table; widths are in twentieths of a point and
+// the first row is shaded as a header when header is set.
+func (d *docxBody) table(widths []int, header bool, rows [][]docxRun) {
+	d.sb.WriteString(`<w:tbl><w:tblPr><w:tblStyle w:val="TableGrid"/>`)
+	d.sb.WriteString(fmt.Sprintf(`<w:tblW w:w="%d" w:type="dxa"/><w:tblLayout w:type="fixed"/></w:tblPr><w:tblGrid>`, docxTextWidth))
+	for _, w := range widths {
+		d.sb.WriteString(fmt.Sprintf(`<w:gridCol w:w="%d"/>`, w))
+	}
+	d.sb.WriteString("</w:tblGrid>")
+	for i, row := range rows {
+		d.sb.WriteString("<w:tr>")
+		if i == 0 && header {
+			d.sb.WriteString("<w:trPr><w:tblHeader/></w:trPr>")
+		}
+		for j, cell := range row {
+			d.sb.WriteString(fmt.Sprintf(`<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/>`, widths[j]))
+			if (i == 0 && header) || (!header && j == 0) {
+				d.sb.WriteString(`<w:shd w:val="clear" w:color="auto" w:fill="F2F2F2"/>`)
+			}
+			d.sb.WriteString("</w:tcPr><w:p>" + docxRuns(cell) + "</w:p></w:tc>")
+		}
+		d.sb.WriteString("</w:tr>")
+	}
+	d.sb.WriteString("</w:tbl>")
+	// Word merges adjacent tables without a paragraph between them.
+	d.sb.WriteString("<w:p/>")
+}
+
+// fields writes a two-column label/value table.
+func (d *docxBody) fields(rows [][2]docxRun) {
+	cells := [][]docxRun{}
+	for _, row := range rows {
+		cells = append(cells, []docxRun{row[0], row[1]})
+	}
+	d.table([]int{docxLabelWidth, docxTextWidth - docxLabelWidth}, false, cells)
+}
+
+func docxLabel(text string) docxRun { return docxRun{text: text, bold: true} }
+func docxText(text string) docxRun  { return docxRun{text: text} }
+
+func docxStatus(status, text string) docxRun {
+	return docxRun{text: text, bold: true, color: docxStatusColors[status]}
+}
+
+func docxDocument(view ReportView) string {
+	d := &docxBody{}
+	host := view.Host
+
+	d.text("Title", "信创基线检查报告")
+	cover := [][2]docxRun{{docxLabel("主机名"), docxText(valueOr(host.Hostname, "未知"))}}
+	if len(host.Addresses) > 0 {
+		cover = append(cover, [2]docxRun{docxLabel("IP地址"), docxText(strings.Join(host.Addresses, ", "))})
+	}
+	cover = append(cover, [2]docxRun{docxLabel("操作系统"), docxText(host.OS)})
+	if host.Kernel != "" {
+		cover = append(cover, [2]docxRun{docxLabel("内核版本"), docxText(host.Kernel)})
+	}
+	if host.Root != "" {
+		cover = append(cover, [2]docxRun{docxLabel("离线扫描"), docxText(host.Root)})
+	}
+	cover = append(cover,
+		[2]docxRun{docxLabel("基线配置"), docxText(view.Output.Profile.Name + " (" + view.Output.Profile.Hash + ")")},
+		[2]docxRun{docxLabel("检查时间"), docxText(host.CheckedAt.Format("2006-01-02 15:04:05"))},
+		[2]docxRun{docxLabel("检查人"), docxText(view.Inspector)},
+	)
+	d.fields(cover)
+	d.pageBreak()
+
+	d.text("Heading1", "一、检查结论")
+	sum := view.Output.Summary
+	score := docxText("无可评分检查项")
+	if sum.Total > 0 {
+		score = docxText(fmt.Sprintf("%.1f（等级 %s，加权 %d/%d）", sum.Score, sum.Grade, sum.Earned, sum.Total))
+	}
+	counts := []string{}
+	for _, c := range view.Counts {
+		counts = append(counts, fmt.Sprintf("%s %d", c.Label, c.Count))
+	}
+	d.fields([][2]docxRun{
+		{docxLabel("合规得分"), score},
+		{docxLabel("检查项数"), docxText(fmt.Sprintf("%d（%s）", len(view.Items), strings.Join(counts, "，")))},
+	})
+	overview := [][]docxRun{{docxLabel("序号"), docxLabel("检查项"), docxLabel("级别"), docxLabel("状态")}}
+	for i, item := range view.Items {
+		overview = append(overview, []docxRun{
+			docxText(fmt.Sprint(i + 1)),
+			docxText(item.Name),
+			docxText(item.SeverityLabel),
+			docxStatus(item.Status, item.StatusLabel),
+		})
+	}
+	d.table([]int{800, docxTextWidth - 800 - 1000 - 1600, 1000, 1600}, true, overview)
+
+	d.text("Heading1", "二、检查项详情")
+	for i, item := range view.Items {
+		d.text("Heading2", fmt.Sprintf("%d. %s", i+1, item.Name))
+		rows := [][2]docxRun{
+			{docxLabel("检查项ID"), docxText(item.ID)},
+			{docxLabel("说明"), docxText(item.Desc)},
+			{docxLabel("期望"), docxText(item.Expected)},
+			{docxLabel("当前"), docxText(item.Current)},
+			{docxLabel("级别"), docxText(item.SeverityLabel)},
+			{docxLabel("状态"), docxStatus(item.Status, item.StatusLabel)},
+		}
+		if len(item.Failed) > 0 {
+			lines := []string{}
+			for _, f := range item.Failed {
+				lines = append(lines, findingLine(f))
+			}
+			rows = append(rows, [2]docxRun{docxLabel("不符合项"), docxText(strings.Join(lines, "\n"))})
+		}
+		if w := item.Waiver; w != nil {
+			state := "豁免"
+			if w.Expired {
+				state = "豁免（已过期）"
+			}
+			text := fmt.Sprintf("%s（审批人 %s，有效期至 %s）", w.Justification, w.Approver, w.Expires)
+			if len(w.Findings) > 0 {
+				text += " 范围: " + strings.Join(w.Findings, ", ")
+			}
+			rows = append(rows, [2]docxRun{docxLabel(state), docxText(text)})
+		}
+		if item.Hint != "" {
+			rows = append(rows, [2]docxRun{docxLabel("修复指引"), docxText(item.Hint)})
+		}
+		d.fields(rows)
+	}
+
+	return xml.Header + `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		d.sb.String() +
+		fmt.Sprintf(`<w:sectPr><w:pgSz w:w="%d" w:h="%d"/><w:pgMar w:top="%d" w:right="%d" w:bottom="%d" w:left="%d" w:header="851" w:footer="992" w:gutter="0"/></w:sectPr>`,
+			docxPageWidth, docxPageHeight, docxMargin, docxMargin, docxMargin, docxMargin) +
+		`</w:body></w:document>`
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+const docxContentTypes = `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+	`<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>` +
+	`<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>` +
+	`</Types>`
+
+const docxRels = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>` +
+	`</Relationships>`
+
+const docxDocumentRels = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// Body text in 宋体 五号, headings in 黑体.
+const docxStyles = `<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+	`<w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:ascii="Times New Roman" w:hAnsi="Times New Roman" w:eastAsia="宋体" w:cs="Times New Roman"/>` +
+	`<w:sz w:val="21"/><w:szCs w:val="21"/><w:lang w:val="en-US" w:eastAsia="zh-CN"/></w:rPr></w:rPrDefault>` +
+	`<w:pPrDefault><w:pPr><w:spacing w:after="60" w:line="300" w:lineRule="auto"/></w:pPr></w:pPrDefault></w:docDefaults>` +
+	`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/><w:basedOn w:val="Normal"/>` +
+	`<w:pPr><w:spacing w:before="2400" w:after="1200"/><w:jc w:val="center"/></w:pPr><w:rPr><w:rFonts w:eastAsia="黑体"/><w:b/><w:sz w:val="52"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/>` +
+	`<w:pPr><w:keepNext/><w:spacing w:before="360" w:after="160"/><w:outlineLvl w:val="0"/></w:pPr><w:rPr><w:rFonts w:eastAsia="黑体"/><w:b/><w:sz w:val="32"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/>` +
+	`<w:pPr><w:keepNext/><w:spacing w:before="240" w:after="120"/><w:outlineLvl w:val="1"/></w:pPr><w:rPr><w:rFonts w:eastAsia="黑体"/><w:b/><w:sz w:val="26"/></w:rPr></w:style>` +
+	`<w:style w:type="table" w:styleId="TableGrid"><w:name w:val="Table Grid"/><w:tblPr><w:tblBorders>` +
+	`<w:top w:val="single" w:sz="4" w:space="0" w:color="808080"/><w:left w:val="single" w:sz="4" w:space="0" w:color="808080"/>` +
+	`<w:bottom w:val="single" w:sz="4" w:space="0" w:color="808080"/><w:right w:val="single" w:sz="4" w:space="0" w:color="808080"/>` +
+	`<w:insideH w:val="single" w:sz="4" w:space="0" w:color="808080"/><w:insideV w:val="single" w:sz="4" w:space="0" w:color="808080"/>` +
+	`</w:tblBorders><w:tblCellMar><w:left w:w="108" w:type="dxa"/><w:right w:w="108" w:type="dxa"/></w:tblCellMar></w:tblPr></w:style>` +
+	`</w:styles>`
+
+func docxCore(view ReportView) string {
+	creator := valueOr(view.Inspector, "xc-baseline-go")
+	return `<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" ` +
+		`xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<dc:title>` + docxEscape("信创基线检查报告 - "+view.Host.Hostname) + `</dc:title>` +
+		`<dc:creator>` + docxEscape(creator) + `</dc:creator>` +
+		`<dcterms:created xsi:type="dcterms:W3CDTF">` + view.Host.CheckedAt.UTC().Format("2006-01-02T15:04:05Z") + `</dcterms:created>` +
+		`</cp:coreProperties>`
+}
+
+func writeDocxReport(out io.Writer, view ReportView) error {
+	return writeOOXMLPackage(out, view.Host.CheckedAt, []ooxmlPart{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxRels},
+		{"docProps/core.xml", docxCore(view)},
+		{"word/_rels/document.xml.rels", docxDocumentRels},
+		{"word/styles.xml", docxStyles},
+		{"word/document.xml", docxDocument(view)},
+	})
+}
+
+type ooxmlPart struct{ name, content string }
+
+// writeOOXMLPackage zips the parts of a docx or xlsx file, adding the XML
+// declaration where a part lacks one.
+func writeOOXMLPackage(out io.Writer, modified time.Time, parts []ooxmlPart) error {
+	zw := zip.NewWriter(out)
+	for _, part := range parts {
+		content := part.content
+		if !strings.HasPrefix(content, "<?xml") {
+			content = xml.Header + content
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: part.name, Method: zip.Deflate, Modified: modified})
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// DriftReport lists what changed between two --check --format json results.
+type DriftReport struct {
+	Old     string       `json:"old"`
+	New     string       `json:"new"`
+	OS      *ValueChange `json:"os,omitempty"`
+	Profile *ValueChange `json:"profile,omitempty"`
+	Score   *ValueChange `json:"score,omitempty"`
+	Items   []ItemDrift  `json:"items"`
+}
+
+type ValueChange struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// ItemDrift is one item whose status or failing findings changed. Before
+// or After is empty when the item is missing from that result.
+type ItemDrift struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Before   string    `json:"before"`
+	After    string    `json:"after"`
+	New      []Finding `json:"new_findings"`
+	Resolved []Finding `json:"resolved_findings"`
+}
+
+func loadOutput(path string) (Output, error) {
+	var out Output
+	if err := readJSONFile(path, &out); err != nil {
+		return out, fmt.Errorf("%s: %v", path, err)
+	}
+	if out.Items == nil {
+		return out, fmt.Errorf("%s: 不是 --check --format json 结果文件", path)
+	}
+	return out, nil
+}
+
+func valueChange(before, after string) *ValueChange {
+	if before == after {
+		return nil
+	}
+	return &ValueChange{Before: before, After: after}
+}
+
+func scoreLabel(sum Summary) string {
+	if sum.Total == 0 {
+		return "N/A"
+	}
+	return fmt.Sprintf("%.1f (%s)", sum.Score, sum.Grade)
+}
+
+func diffOutputs(oldPath, newPath string, before, after Output) DriftReport {
+	report := DriftReport{
+		Old:     oldPath,
+		New:     newPath,
+		OS:      valueChange(before.OS, after.OS),
+		Profile: valueChange(before.Profile.Name+" "+before.Profile.Hash, after.Profile.Name+" "+after.Profile.Hash),
+		Score:   valueChange(scoreLabel(before.Summary), scoreLabel(after.Summary)),
+		Items:   []ItemDrift{},
+	}
+	oldItems := map[string]OutputItem{}
+	for _, item := range before.Items {
+		oldItems[item.ID] = item
+	}
+	seen := map[string]bool{}
+	for _, item := range after.Items {
+		seen[item.ID] = true
+		prev, ok := oldItems[item.ID]
+		drift := ItemDrift{ID: item.ID, Name: item.Name, After: item.Status}
+		if ok {
+			drift.Before = prev.Status
+		}
+		drift.New = findingsOnlyIn(failedFindings(item.Findings), failedFindings(prev.Findings))
+		drift.Resolved = findingsOnlyIn(failedFindings(prev.Findings), failedFindings(item.Findings))
+		if drift.Before != drift.After || len(drift.New) > 0 || len(drift.Resolved) > 0 {
+			report.Items = append(report.Items, drift)
+		}
+	}
+	for _, item := range before.Items {
+		if seen[item.ID] {
+			continue
+		}
+		report.Items = append(report.Items, ItemDrift{
+			ID: item.ID, Name: item.Name, Before: item.Status,
+			New: []Finding{}, Resolved: failedFindings(item.Findings),
+		})
+	}
+	return report
+}
+
+// findingsOnlyIn returns the findings of a whose key is not in b.
+func findingsOnlyIn(a, b []Finding) []Finding {
+	keys := map[string]bool{}
+	for _, f := range b {
+		keys[f.Key] = true
+	}
+	out := []Finding{}
+	for _, f := range a {
+		if !keys[f.Key] {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func printDriftReport(report DriftReport, jsonOut bool, outputFile string) error {
+	var out io.Writer = os.Stdout
+	if outputFile != "" {
+		file, err := os.Create(outputFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	if jsonOut {
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	fmt.Fprintf(out, "基线变化: %s → %s\n", report.Old, report.New)
+	fmt.Fprintln(out, "============================================================")
+	for _, change := range []struct {
+		label string
+		value *ValueChange
+	}{{"系统版本", report.OS}, {"基线配置", report.Profile}, {"合规得分", report.Score}} {
+		if change.value != nil {
+			fmt.Fprintf(out, "%s: %s → %s\n", change.label, change.value.Before, change.value.After)
+		}
+	}
+	if len(report.Items) == 0 {
+		fmt.Fprintln(out, "检查项无变化")
+	}
+	for _, item := range report.Items {
+		fmt.Fprintln(out, "------------------------------------------------------------")
+		before, after := valueOrNA(item.Before), valueOrNA(item.After)
+		switch {
+		case item.After == "fail" && item.Before != "fail":
+			after = colorize("red", after)
+		case item.Before == "fail" && item.After != "fail" && item.After != "":
+			after = colorize("green", after)
+		}
+		fmt.Fprintf(out, "[%s] %s: %s → %s\n", item.ID, item.Name, before, after)
+		for _, f := range item.New {
+			fmt.Fprintf(out, "  + %s\n", findingLine(f))
+		}
+		for _, f := range item.Resolved {
+			fmt.Fprintf(out, "  - %s\n", findingLine(f))
+		}
+	}
+	fmt.Fprintln(out, "============================================================")
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import "fmt"
+
+// Process exit codes of --check/--replay, for wrapping scripts and
+// provisioning pipelines.
+const (
+	exitPass         = 0 // every gated item passed
+	exitFail         = 1 // at least one gated item failed
+	exitInconclusive = 2 // no failures, but some gated item is manual/timeout/error
+	exitError        = 3 // the tool itself could not run (bad flags, profile, output)
+	// exitBadSignature is --verify's failure, kept apart from exitFail so a
+	// collector never mistakes a forged result for a failing host.
+	exitBadSignature = 4
+)
+
+var severityRank = map[string]int{"low": 1, "medium": 2, "high": 3}
+
+func parseFailOn(value string) (int, error) {
+	rank, ok := severityRank[value]
+	if !ok {
+		return 0, fmt.Errorf("--fail-on 仅支持 high/medium/low: %s", value)
+	}
+	return rank, nil
+}
+
+// exitCodeFor only considers items at or above the --fail-on severity;
+// items without a known severity are always gated.
+func exitCodeFor(items []OutputItem, minRank int) int {
+	code := exitPass
+	for _, item := range items {
+		if rank, ok := severityRank[item.Severity]; ok && rank < minRank {
+			continue
+		}
+		switch item.Status {
+		case "fail":
+			return exitFail
+		case "manual", "timeout", "error":
+			code = exitInconclusive
+		}
+	}
+	return code
+}
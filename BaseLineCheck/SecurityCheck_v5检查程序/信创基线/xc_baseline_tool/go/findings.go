@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Finding is one typed sub-result of an item. Key is stable across
+// versions and locales so fleet tooling can aggregate on it; Label is the
+// human-readable name shown in reports.
+type Finding struct {
+	Key      string    `json:"key"`
+	Label    string    `json:"label,omitempty"`
+	Observed string    `json:"observed"`
+	Expected string    `json:"expected"`
+	Status   string    `json:"status"`
+	Source   *Evidence `json:"source,omitempty"`
+}
+
+// Evidence points at where a finding was observed: a file line or the
+// command that was run.
+type Evidence struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Command string `json:"command,omitempty"`
+}
+
+func (e *Evidence) String() string {
+	if e == nil {
+		return ""
+	}
+	if e.Command != "" {
+		return "$ " + e.Command
+	}
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d", e.File, e.Line)
+	}
+	return e.File
+}
+
+func passFail(ok bool) string {
+	if ok {
+		return "pass"
+	}
+	return "fail"
+}
+
+func fileFinding(key, label, observed, expected string, ok bool, file string, line int) Finding {
+	f := Finding{Key: key, Label: label, Observed: observed, Expected: expected, Status: passFail(ok)}
+	if file != "" {
+		f.Source = &Evidence{File: file, Line: line}
+	}
+	return f
+}
+
+func commandFinding(key, label, observed, expected string, ok bool, command string) Finding {
+	f := Finding{Key: key, Label: label, Observed: observed, Expected: expected, Status: passFail(ok)}
+	if command != "" {
+		f.Source = &Evidence{Command: command}
+	}
+	return f
+}
+
+// lineOf returns the 1-based line of the first (or last) match of pattern
+// in content, or 0 when it does not match.
+func lineOf(content, pattern string, last bool) int {
+	re := regexp.MustCompile(pattern)
+	found := 0
+	for i, line := range strings.Split(content, "\n") {
+		if re.MatchString(line) {
+			found = i + 1
+			if !last {
+				return found
+			}
+		}
+	}
+	return found
+}
+
+// failedFindings keeps the findings that explain a non-passing item;
+// waived findings are accepted risks and are left out.
+func failedFindings(findings []Finding) []Finding {
+	out := []Finding{}
+	for _, f := range findings {
+		if f.Status != "pass" && f.Status != "info" && f.Status != "waived" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// findingLine renders a finding for text reports.
+func findingLine(f Finding) string {
+	label := f.Label
+	if label == "" {
+		label = f.Key
+	}
+	line := fmt.Sprintf("%s: %s（期望 %s）", label, f.Observed, f.Expected)
+	if src := f.Source.String(); src != "" {
+		line += " 来源: " + src
+	}
+	return line
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// The generated script only uses helpers from this prelude. Each helper
+// compares before writing, so re-running the script changes nothing on a
+// host that is already fixed, and backs a file up once before its first
+// change.
+const fixScriptPrelude = `set -euo pipefail
+
+if [ "$(id -u)" -ne 0 ]; then
+  echo "请以root权限执行" >&2
+  exit 1
+fi
+
+BACKUP_DIR="/var/lib/xc-baseline/script-backups/$(date +%Y%m%d-%H%M%S)"
+
+backup() {
+  [ -e "$1" ] || return 0
+  [ -e "$BACKUP_DIR$1" ] && return 0
+  mkdir -p "$BACKUP_DIR$(dirname "$1")"
+  cp -a "$1" "$BACKUP_DIR$1"
+}
+
+# replace_if_changed FILE TMP: install TMP as FILE only when content differs.
+replace_if_changed() {
+  if [ -f "$1" ] && cmp -s "$1" "$2"; then
+    rm -f "$2"
+    return 0
+  fi
+  backup "$1"
+  mkdir -p "$(dirname "$1")"
+  cat "$2" > "$1"
+  rm -f "$2"
+  echo "已更新: $1"
+}
+
+# write_file FILE CONTENT
+write_file() {
+  local tmp
+  tmp=$(mktemp)
+  printf '%s\n' "$2" > "$tmp"
+  replace_if_changed "$1" "$tmp"
+}
+
+# ensure_line FILE REGEX LINE [ANCHOR]: lines matching REGEX become LINE;
+# without a match LINE is inserted before the first ANCHOR match, or appended.
+ensure_line() {
+  local file="$1" regex="$2" line="$3" anchor="${4:-}" tmp
+  tmp=$(mktemp)
+  [ -f "$file" ] && cat "$file" > "$tmp"
+  if grep -Eq "$regex" "$tmp"; then
+    awk -v re="$regex" -v line="$line" '$0 ~ re { print line; next } { print }' "$tmp" > "$tmp.new"
+  elif [ -n "$anchor" ] && grep -Eq "$anchor" "$tmp"; then
+    awk -v re="$anchor" -v line="$line" '!done && $0 ~ re { print line; done=1 } { print }' "$tmp" > "$tmp.new"
+  else
+    { cat "$tmp"; printf '%s\n' "$line"; } > "$tmp.new"
+  fi
+  rm -f "$tmp"
+  replace_if_changed "$file" "$tmp.new"
+}
+
+# set_kv FILE KEY VALUE SEP: set an active "KEY SEP VALUE" line; comments are kept.
+set_kv() {
+  ensure_line "$1" "^[[:space:]]*$2([[:space:]]|=|$)" "$2$4$3"
+}
+
+# set_ini FILE SECTION KEY VALUE: set KEY=VALUE inside [SECTION] of a keyfile.
+set_ini() {
+  local file="$1" tmp
+  tmp=$(mktemp)
+  [ -f "$file" ] && cat "$file" > "$tmp"
+  awk -v s="[$2]" -v k="$3" -v v="$4" '
+    $0 == s { print; insec=1; next }
+    /^\[/ { if (insec && !done) { print k "=" v; done=1 } insec=0 }
+    insec && index($0, k "=") == 1 { if (!done) print k "=" v; done=1; next }
+    { print }
+    END { if (!done) { if (!insec) print s; print k "=" v } }' "$tmp" > "$tmp.new"
+  rm -f "$tmp"
+  replace_if_changed "$file" "$tmp.new"
+}
+
+# append_once FILE LINE
+append_once() {
+  [ -f "$1" ] && grep -qxF "$2" "$1" && return 0
+  backup "$1"
+  mkdir -p "$(dirname "$1")"
+  printf '%s\n' "$2" >> "$1"
+  echo "已更新: $1"
+}
+
+pam_module_exists() {
+  local dir
+  for dir in /lib/security /lib64/security /lib/x86_64-linux-gnu/security /lib/aarch64-linux-gnu/security /usr/lib/security /usr/lib64/security; do
+    [ -e "$dir/$1" ] && return 0
+  done
+  return 1
+}
+
+pkg_install() {
+  if command -v apt-get >/dev/null 2>&1; then
+    DEBIAN_FRONTEND=noninteractive apt-get install -y "$@"
+  elif command -v dnf >/dev/null 2>&1; then
+    dnf install -y "$@"
+  elif command -v yum >/dev/null 2>&1; then
+    yum install -y "$@"
+  else
+    echo "未找到包管理器，请手动安装: $*" >&2
+    return 1
+  fi
+}
+`
+
+// pamPlaceShell places one PAM line the way ensurePamLine does, for the fix
+// script and the Ansible role. The awk program must stay free of single
+// quotes; strings reach it through the environment so awk -v does not
+// rewrite backslashes.
+const pamPlaceShell = `# pam_place FILE NEEDLE LINE TYPE ANCHOR WHERE: the first live line containing
+# NEEDLE becomes LINE; otherwise LINE goes before/after (WHERE) the first live
+# TYPE line of module ANCHOR, or is appended (WHERE=append). Bracketed [key=N]
+# jumps of earlier TYPE lines that skip over the new line are widened by one.
+# An auth stack that would deny a correct password is refused, file untouched.
+PAM_AWK='
+function live(s) { return s !~ /^[ \t]*(#|$)/ }
+function fields(s,   n, f, i) {
+  T = ""; C = ""; M = ""
+  if (!live(s)) return
+  n = split(s, f, /[ \t]+/)
+  i = (f[1] == "") ? 2 : 1
+  T = f[i]; sub(/^-/, "", T)
+  C = f[++i]
+  if (C ~ /^\[/) while (C !~ /\]/ && i < n) C = C " " f[++i]
+  M = f[++i]; sub(/.*\//, "", M)
+}
+function widen(ctrl, p, pos,   out, kv, eq, n) {
+  out = ""
+  while (match(ctrl, /[A-Za-z0-9_]+=[0-9]+/)) {
+    kv = substr(ctrl, RSTART, RLENGTH)
+    eq = index(kv, "=")
+    n = substr(kv, eq + 1) + 0
+    if (pos <= p + n) kv = substr(kv, 1, eq) (n + 1)
+    out = out substr(ctrl, 1, RSTART - 1) kv
+    ctrl = substr(ctrl, RSTART + RLENGTH)
+  }
+  return out ctrl
+}
+function insert(at, line, typ,   i, p, pos, n, S, b, e) {
+  n = 0; pos = -1
+  for (i = 1; i <= N; i++) {
+    fields(L[i])
+    if (T != typ) continue
+    if (pos < 0 && i >= at) pos = n
+    S[n++] = i
+  }
+  if (pos < 0) pos = n
+  for (p = 0; p < pos; p++) {
+    i = S[p]; fields(L[i])
+    b = index(L[i], "["); e = index(L[i], "]")
+    if (C !~ /^\[/ || e < b) continue
+    L[i] = substr(L[i], 1, b - 1) widen(substr(L[i], b, e - b + 1), p, pos) substr(L[i], e + 1)
+  }
+  for (i = N; i >= at; i--) L[i + 1] = L[i]
+  L[at] = line; N++
+}
+function denies(c, m) { return m == "pam_deny.so" || index(c, "default=die") }
+function badauth(   i, n, SC, SM, u, nx, c, kv) {
+  n = 0
+  for (i = 1; i <= N; i++) { fields(L[i]); if (T == "auth") { SC[n] = C; SM[n++] = M } }
+  u = -1
+  for (i = 0; i < n; i++) {
+    if (SM[i] == "pam_unix.so") { u = i; break }
+    if (denies(SC[i], SM[i])) return "认证栈中 " SM[i] " 位于 pam_unix.so 之前"
+  }
+  if (u < 0) return "认证栈中缺少 pam_unix.so"
+  c = SC[u]; nx = u + 1
+  if (c == "sufficient" || index(c, "success=done")) return ""
+  while (match(c, /[A-Za-z0-9_]+=[0-9]+/)) {
+    kv = substr(c, RSTART, RLENGTH)
+    if (kv ~ /^success=/) nx += substr(kv, 9) + 0
+    c = substr(c, RSTART + RLENGTH)
+  }
+  if (nx < n && denies(SC[nx], SM[nx])) return "pam_unix.so 认证成功后将执行 " SM[nx] "，正确密码也会被拒绝"
+  return ""
+}
+{ L[++N] = $0 }
+END {
+  needle = ENVIRON["PAM_NEEDLE"]; line = ENVIRON["PAM_LINE"]; typ = ENVIRON["PAM_TYPE"]
+  anchor = ENVIRON["PAM_ANCHOR"]; where = ENVIRON["PAM_WHERE"]
+  at = 0
+  for (i = 1; i <= N; i++) if (live(L[i]) && index(L[i], needle)) { L[i] = line; at = -1; break }
+  if (at == 0 && where == "append") { L[++N] = line; at = -1 }
+  if (at == 0) {
+    for (i = 1; i <= N && !at; i++) { fields(L[i]); if (T == typ && M == anchor) at = i }
+    if (!at) { print "未找到 " typ " " anchor " 行，无法定位: " line > "/dev/stderr"; exit 1 }
+    if (where == "after") at++
+    insert(at, line, typ)
+  }
+  if (typ == "auth" && (msg = badauth()) != "") { print msg > "/dev/stderr"; exit 1 }
+  for (i = 1; i <= N; i++) print L[i]
+}'
+
+pam_place() {
+  local file="$1" tmp
+  tmp=$(mktemp)
+  [ -f "$file" ] && cat "$file" > "$tmp"
+  if ! PAM_NEEDLE="$2" PAM_LINE="$3" PAM_TYPE="$4" PAM_ANCHOR="$5" PAM_WHERE="$6" awk "$PAM_AWK" "$tmp" > "$tmp.new"; then
+    rm -f "$tmp" "$tmp.new"
+    echo "未修改 $file" >&2
+    return 1
+  fi
+  rm -f "$tmp"
+  replace_if_changed "$file" "$tmp.new"
+}
+`
+
+// pamPlaceArgs are the pam_place arguments after FILE for pl.
+func pamPlaceArgs(pl pamLine) []string {
+	typ, _, _ := pamFields(pl.Line)
+	switch pl.Place {
+	case pamBeforeUnix:
+		return []string{pl.Needle, pl.Line, typ, "pam_unix.so", "before"}
+	case pamAfterUnix:
+		return []string{pl.Needle, pl.Line, typ, "pam_unix.so", "after"}
+	}
+	return []string{pl.Needle, pl.Line, typ, "", "append"}
+}
+
+func shellArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+type scriptWriter struct {
+	sb strings.Builder
+}
+
+func (w *scriptWriter) line(format string, args ...interface{}) {
+	fmt.Fprintf(&w.sb, format+"\n", args...)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// emitFixScript writes a bash script that remediates the failed items of
+// payload for the detected distro. Nothing is executed here.
+func emitFixScript(rc *RunContext, payload Output, path string) error {
+	w := &scriptWriter{}
+	failed := []OutputItem{}
+	for _, item := range payload.Items {
+		if item.Status == "fail" {
+			failed = append(failed, item)
+		}
+	}
+	ids := []string{}
+	for _, item := range failed {
+		ids = append(ids, item.ID)
+	}
+	w.line("#!/usr/bin/env bash")
+	w.line("# 信创基线修复脚本：由 xc-baseline-go --emit-fix-script 生成，执行前请审阅。")
+	w.line("# 系统: %s", payload.OS)
+	w.line("# 基线配置: %s (%s)", payload.Profile.Name, payload.Profile.Hash)
+	w.line("# 生成时间: %s", time.Now().Format(time.RFC3339))
+	w.line("# 修复项: %s", strings.Join(ids, ", "))
+	w.line("# 可重复执行：已符合的配置不会再次写入；修改前的文件备份在 /var/lib/xc-baseline/script-backups/ 下。")
+	w.sb.WriteString(fixScriptPrelude)
+	w.line("")
+	w.sb.WriteString(pamPlaceShell)
+	if len(failed) == 0 {
+		w.line("")
+		w.line("echo \"没有需要修复的检查项\"")
+	}
+	for _, item := range failed {
+		w.line("")
+		w.line("# ---- [%s] %s ----", item.ID, item.Name)
+		w.line("echo \"==> [%s] %s\"", item.ID, item.Name)
+		if !writeFixSection(rc, w, item.ID) {
+			if hint := manualFixHint(rc, item.ID); hint != "" {
+				w.line("# 无自动修复脚本，参考: %s", hint)
+			}
+			w.line("echo \"[%s] 需人工处理\" >&2", item.ID)
+		}
+	}
+	w.line("")
+	w.line("echo \"完成。请重新执行 xc-baseline-go --check 复核。\"")
+	return os.WriteFile(path, []byte(w.sb.String()), 0700)
+}
+
+func writeFixSection(rc *RunContext, w *scriptWriter, id string) bool {
+	kind := rc.Distro
+	switch id {
+	case "ftp_service":
+		w.line("for svc in vsftpd proftpd pure-ftpd ftpd; do")
+		w.line("  if systemctl is-active --quiet \"$svc\" 2>/dev/null || systemctl is-enabled --quiet \"$svc\" 2>/dev/null; then")
+		w.line("    systemctl disable --now \"$svc\"")
+		w.line("  fi")
+		w.line("done")
+	case "risky_ports":
+		writeFirewallSection(rc, w)
+	case "usb_autoplay":
+		writeDconfSection(w, usbAutoplaySettings())
+	case "ipv6_disabled":
+		for _, key := range ipv6SysctlKeys() {
+			w.line("set_kv %s %s 1 ' = '", ipv6SysctlPath, key)
+		}
+		w.line("sysctl -p %s", ipv6SysctlPath)
+	case "patch_updates":
+		w.line("if command -v apt-get >/dev/null 2>&1; then")
+		w.line("  apt-get update")
+		w.line("  DEBIAN_FRONTEND=noninteractive apt-get -y upgrade")
+		w.line("elif command -v dnf >/dev/null 2>&1; then")
+		w.line("  dnf -y upgrade")
+		w.line("elif command -v yum >/dev/null 2>&1; then")
+		w.line("  yum -y update")
+		w.line("fi")
+	case "password_policy":
+		writePasswordSection(rc, w)
+	case "lock_screen":
+		writeDconfSection(w, lockScreenSettings(rc.Profile))
+	case "audit_rules":
+		pkg := "auditd"
+		if kind.IsKylin || kind.IsNeoKylin {
+			pkg = "audit"
+		}
+		w.line("command -v auditctl >/dev/null 2>&1 || pkg_install %s", pkg)
+		w.line("systemctl enable --now auditd")
+		rules := remediationAuditRules(rc.Profile)
+		w.line("write_file /etc/audit/rules.d/xc-baseline.rules %s", shellQuote(strings.Join(rules, "\n")))
+		w.line("if command -v augenrules >/dev/null 2>&1; then augenrules --load; else service auditd restart; fi")
+	default:
+		return false
+	}
+	return true
+}
+
+func writeDconfSection(w *scriptWriter, settings []dconfSetting) {
+	for _, setting := range settings {
+		w.line("set_ini %s %s %s %s", dconfConfigPath, setting.Section, setting.Key, shellQuote(setting.Value))
+	}
+	for _, setting := range settings {
+		w.line("append_once %s %s", dconfLockPath, setting.lockPath())
+	}
+	w.line("if command -v dconf >/dev/null 2>&1; then dconf update; fi")
+}
+
+// writeFirewallSection picks the backend the checks expect on each distro:
+// ufw on UOS, firewalld (or iptables without it) on Kylin, nftables
+// elsewhere. UOS and Kylin are only checked for inbound blocks.
+func writeFirewallSection(rc *RunContext, w *scriptWriter) {
+	kind := rc.Distro
+	ports := []string{}
+	for _, port := range rc.Profile.RiskyPorts {
+		ports = append(ports, fmt.Sprintf("%d", port))
+	}
+	portList := strings.Join(ports, " ")
+	switch {
+	case kind.IsUOS:
+		w.line("command -v ufw >/dev/null 2>&1 || pkg_install ufw")
+		w.line("for port in %s; do", portList)
+		w.line("  for proto in tcp udp; do")
+		w.line("    ufw deny \"$port/$proto\" >/dev/null")
+		w.line("  done")
+		w.line("done")
+		w.line("# 启用后默认拒绝入站连接，请确认业务端口已放行（ufw allow PORT/tcp）。")
+		w.line("ufw status | grep -q 'Status: active' || ufw --force enable")
+	case kind.IsKylin || kind.IsNeoKylin:
+		w.line("if command -v firewall-cmd >/dev/null 2>&1; then")
+		w.line("  systemctl enable --now firewalld")
+		w.line("  for port in %s; do", portList)
+		w.line("    for proto in tcp udp; do")
+		w.line("      for family in ipv4 ipv6; do")
+		w.line("        rule=\"rule family=\\\"$family\\\" port port=\\\"$port\\\" protocol=\\\"$proto\\\" reject\"")
+		w.line("        firewall-cmd --permanent --query-rich-rule=\"$rule\" >/dev/null 2>&1 || firewall-cmd --permanent --add-rich-rule=\"$rule\" >/dev/null")
+		w.line("      done")
+		w.line("    done")
+		w.line("  done")
+		w.line("  firewall-cmd --reload")
+		w.line("else")
+		w.line("  for port in %s; do", portList)
+		w.line("    for proto in tcp udp; do")
+		w.line("      iptables -C INPUT -p \"$proto\" --dport \"$port\" -j DROP 2>/dev/null || iptables -A INPUT -p \"$proto\" --dport \"$port\" -j DROP")
+		w.line("    done")
+		w.line("  done")
+		w.line("  if [ -d /etc/sysconfig ]; then")
+		w.line("    backup /etc/sysconfig/iptables")
+		w.line("    iptables-save > /etc/sysconfig/iptables")
+		w.line("  fi")
+		w.line("fi")
+	default:
+		rules := []string{"table inet xc_baseline {"}
+		for _, chain := range []string{"input", "output"} {
+			rules = append(rules, fmt.Sprintf("  chain %s {", chain), fmt.Sprintf("    type filter hook %s priority 0; policy accept;", chain))
+			for _, port := range ports {
+				for _, proto := range []string{"tcp", "udp"} {
+					rules = append(rules, fmt.Sprintf("    %s dport %s counter drop", proto, port))
+				}
+			}
+			rules = append(rules, "  }")
+		}
+		rules = append(rules, "}")
+		w.line("command -v nft >/dev/null 2>&1 || pkg_install nftables")
+		w.line("write_file /etc/xc-baseline/xc-baseline.nft %s", shellQuote(strings.Join(rules, "\n")))
+		w.line("nft list table inet xc_baseline >/dev/null 2>&1 && nft delete table inet xc_baseline")
+		w.line("nft -f /etc/xc-baseline/xc-baseline.nft")
+		w.line("# 开机加载")
+		w.line("[ -f /etc/nftables.conf ] && append_once /etc/nftables.conf 'include \"/etc/xc-baseline/xc-baseline.nft\"'")
+		w.line("systemctl enable nftables >/dev/null 2>&1 || true")
+	}
+}
+
+func writePasswordSection(rc *RunContext, w *scriptWriter) {
+	policy := rc.Profile.Password
+	maxKey, minKey, lenKey := loginDefsKeys(rc.readFile("/etc/login.defs"))
+	for _, kv := range loginDefsSettings(policy, maxKey, minKey, lenKey) {
+		w.line("set_kv /etc/login.defs %s %s '   '", kv.Key, kv.Value)
+	}
+	for _, kv := range pwqualitySettings(policy) {
+		w.line("set_kv /etc/security/pwquality.conf %s %s ' = '", kv.Key, kv.Value)
+	}
+
+	pamFiles, authFiles := pamFilesByDistro(rc)
+	pamFile := rc.firstExistingFile(pamFiles)
+	if pamFile == "" {
+		pamFile = pamFiles[0]
+	}
+	authFile := rc.firstExistingFile(authFiles)
+	if authFile == "" {
+		authFile = authFiles[0]
+	}
+	unixPassword := `^[[:space:]]*password[[:space:]].*pam_unix\.so`
+	w.line("PAM_FILE=%s", pamFile)
+	w.line("ensure_line \"$PAM_FILE\" %s %s %s",
+		shellQuote(`^[[:space:]]*password[[:space:]].*pam_(pwquality|cracklib)\.so`),
+		shellQuote(pwqualityPamLine(policy)),
+		shellQuote(unixPassword))
+	if policy.Remember > 0 {
+		// Keep the distro's pam_unix control flags; only set remember=N.
+		w.line("tmp=$(mktemp)")
+		w.line("sed -E %s \"$PAM_FILE\" > \"$tmp\"",
+			shellQuote(fmt.Sprintf(`/%s/{s/remember=[0-9]+/remember=%d/;t;s/$/ remember=%d/}`, strings.ReplaceAll(unixPassword, "/", `\/`), policy.Remember, policy.Remember)))
+		w.line("replace_if_changed \"$PAM_FILE\" \"$tmp\"")
+	}
+	w.line("AUTH_FILE=%s", authFile)
+	faillock, tally2 := faillockPamLines(policy), tally2PamLines(policy)
+	w.line("if command -v authselect >/dev/null 2>&1 && authselect current >/dev/null 2>&1; then")
+	w.line("  authselect enable-feature with-faillock")
+	w.line("  set_kv /etc/security/faillock.conf deny %d ' = '", policy.Deny)
+	w.line("  set_kv /etc/security/faillock.conf unlock_time %d ' = '", policy.UnlockTime)
+	w.line("elif pam_module_exists pam_faillock.so; then")
+	for _, pl := range faillock {
+		w.line("  pam_place \"$AUTH_FILE\" %s", shellArgs(pamPlaceArgs(pl)))
+	}
+	w.line("elif pam_module_exists pam_tally2.so; then")
+	for _, pl := range tally2 {
+		w.line("  pam_place \"$AUTH_FILE\" %s", shellArgs(pamPlaceArgs(pl)))
+	}
+	w.line("else")
+	w.line("  echo \"未找到 pam_faillock/pam_tally2 模块，登录失败锁定需人工配置\" >&2")
+	w.line("fi")
+}
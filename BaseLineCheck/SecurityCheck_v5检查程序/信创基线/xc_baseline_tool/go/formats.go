@@ -0,0 +1,342 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --format takes a comma-separated list of FORMAT or FORMAT=FILE. A single
+// format without a file goes to --output (or stdout); with several, each
+// unnamed one is written to the --output name with that format's extension.
+// --prom-textfile adds a prom target of its own.
+type outputTarget struct {
+	Format string
+	Path   string
+}
+
+var formatExts = map[string]string{
+	"text":  ".txt",
+	"json":  ".json",
+	"html":  ".html",
+	"docx":  ".docx",
+	"xlsx":  ".xlsx",
+	"junit": ".xml",
+	"csv":   ".csv",
+	"xccdf": ".xccdf.xml",
+	"prom":  ".prom",
+}
+
+// checkFormats are the formats of --check / --replay, in the order they
+// are listed in errors and help.
+var checkFormats = []string{"text", "json", "html", "docx", "xlsx", "junit", "csv", "xccdf", "prom"}
+
+// parseFormat resolves --format (or --json) for commands with a single
+// output.
+func parseFormat(format string, jsonOut bool, allowed ...string) (string, error) {
+	if format == "" {
+		if jsonOut {
+			return "json", nil
+		}
+		return "text", nil
+	}
+	if jsonOut && format != "json" {
+		return "", fmt.Errorf("--json 与 --format %s 冲突", format)
+	}
+	if !containsString(allowed, format) {
+		return "", fmt.Errorf("该命令的 --format 可选: %s", strings.Join(allowed, "/"))
+	}
+	return format, nil
+}
+
+func parseOutputTargets(spec string, jsonOut bool, output, promFile string) ([]outputTarget, error) {
+	if spec == "" {
+		spec = "text"
+		if jsonOut {
+			spec = "json"
+		}
+	} else if jsonOut && spec != "json" {
+		return nil, fmt.Errorf("--json 与 --format %s 冲突", spec)
+	}
+	targets := []outputTarget{}
+	seen := map[string]bool{}
+	for _, entry := range splitList(spec) {
+		format, path, _ := strings.Cut(entry, "=")
+		if !containsString(checkFormats, format) {
+			return nil, fmt.Errorf("未知的输出格式 %s（可选: %s）", format, strings.Join(checkFormats, "/"))
+		}
+		if seen[format] {
+			return nil, fmt.Errorf("--format 中 %s 重复", format)
+		}
+		seen[format] = true
+		targets = append(targets, outputTarget{Format: format, Path: path})
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("--format 为空")
+	}
+	if len(targets) == 1 && targets[0].Path == "" {
+		targets[0].Path = output
+	} else {
+		base := trimFormatExt(output)
+		stdout := 0
+		for i := range targets {
+			if targets[i].Path != "" {
+				continue
+			}
+			if base != "" {
+				targets[i].Path = base + formatExts[targets[i].Format]
+			} else if stdout++; stdout > 1 {
+				return nil, fmt.Errorf("多个输出格式须用 --output 指定文件名前缀，或写成 FORMAT=FILE")
+			}
+		}
+	}
+	if promFile != "" {
+		if seen["prom"] {
+			return nil, fmt.Errorf("--prom-textfile 与 --format prom 不能同时使用")
+		}
+		targets = append(targets, outputTarget{Format: "prom", Path: promFile})
+	}
+	paths := map[string]string{}
+	for _, t := range targets {
+		if (t.Format == "docx" || t.Format == "xlsx") && t.Path == "" {
+			return nil, fmt.Errorf("%s 格式须输出到文件（--output 或 %s=FILE）", t.Format, t.Format)
+		}
+		if t.Path == "" {
+			continue
+		}
+		clean := filepath.Clean(t.Path)
+		if other, ok := paths[clean]; ok {
+			return nil, fmt.Errorf("%s 与 %s 输出到同一文件 %s，请用 FORMAT=FILE 分别指定", other, t.Format, t.Path)
+		}
+		paths[clean] = t.Format
+	}
+	return targets, nil
+}
+
+// trimFormatExt strips the longest known format extension, so r.xccdf.xml
+// yields r rather than r.xccdf.
+func trimFormatExt(output string) string {
+	longest := ""
+	for _, ext := range formatExts {
+		if strings.HasSuffix(output, ext) && len(ext) > len(longest) {
+			longest = ext
+		}
+	}
+	return strings.TrimSuffix(output, longest)
+}
+
+// jsonTargetPath returns the file the JSON result is written to, if any.
+func jsonTargetPath(targets []outputTarget) string {
+	for _, t := range targets {
+		if t.Format == "json" {
+			return t.Path
+		}
+	}
+	return ""
+}
+
+func writeOutput(rc *RunContext, payload Output, target outputTarget) error {
+	if target.Format == "prom" && target.Path != "" {
+		// node_exporter may read the file at any moment.
+		return writeFileAtomic(target.Path, 0644, func(out io.Writer) error {
+			return writePromReport(out, payload, time.Now())
+		})
+	}
+	var out io.Writer = os.Stdout
+	if target.Path != "" {
+		file, err := os.Create(target.Path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	switch target.Format {
+	case "json":
+		// Stable machine-readable output for batch collection.
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(payload)
+	case "html":
+		return writeHTMLReport(out, buildReportView(rc, payload))
+	case "docx":
+		return writeDocxReport(out, buildReportView(rc, payload))
+	case "xlsx":
+		return writeXLSXReport(out, buildReportView(rc, payload))
+	case "junit":
+		return writeJUnitReport(out, payload, readHostname(rc), time.Now())
+	case "csv":
+		return writeCSVReport(out, payload, readHostname(rc))
+	case "prom":
+		return writePromReport(out, payload, time.Now())
+	case "xccdf":
+		return writeXCCDFReport(out, rc.Profile, payload, hostIdentity(rc, payload))
+	}
+	writeTextReport(out, rc, payload)
+	return nil
+}
+
+// JUnit XML lets image-build pipelines show each item as a test case:
+// fail is a failure, timeout/error an error, and items that cannot pass or
+// fail on their own (manual, not_applicable, waived) are skipped.
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Hostname   string          `xml:"hostname,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property"`
+	Cases      []junitCase     `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+func junitSeconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
+
+func writeJUnitReport(out io.Writer, payload Output, host string, now time.Time) error {
+	suite := junitSuite{
+		Name:      "xc-baseline",
+		Timestamp: now.Format("2006-01-02T15:04:05"),
+		Hostname:  host,
+		Properties: []junitProperty{
+			{Name: "os", Value: payload.OS},
+			{Name: "profile", Value: payload.Profile.Name + " " + payload.Profile.Hash},
+			{Name: "score", Value: fmt.Sprintf("%.1f", payload.Summary.Score)},
+			{Name: "grade", Value: payload.Summary.Grade},
+		},
+		Cases: []junitCase{},
+	}
+	if payload.Root != "" {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "root", Value: payload.Root})
+	}
+	var total int64
+	for _, item := range payload.Items {
+		total += item.DurationMS
+		tc := junitCase{
+			Name:      item.ID + " " + item.Name,
+			Classname: "xc-baseline." + valueOr(item.Category, "general"),
+			Time:      junitSeconds(item.DurationMS),
+		}
+		detail := []string{"当前: " + item.Current, "期望: " + item.Expected}
+		for _, f := range failedFindings(item.Findings) {
+			detail = append(detail, "- "+findingLine(f))
+		}
+		switch item.Status {
+		case "fail":
+			suite.Failures++
+			tc.Failure = &junitProblem{Message: item.Current, Type: item.Severity, Body: strings.Join(detail, "\n")}
+		case "timeout", "error":
+			suite.Errors++
+			tc.Error = &junitProblem{Message: item.Current, Type: item.Status, Body: strings.Join(detail, "\n")}
+		case "manual", "not_applicable", "waived":
+			suite.Skipped++
+			message := labelOf(statusLabels, item.Status) + ": " + item.Current
+			if w := item.Waiver; w != nil && item.Status == "waived" {
+				message = fmt.Sprintf("已豁免: %s（审批人 %s，有效期至 %s）", w.Justification, w.Approver, w.Expires)
+			}
+			tc.Skipped = &junitSkipped{Message: message}
+		default:
+			tc.SystemOut = item.Current
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Tests = len(suite.Cases)
+	suite.Time = junitSeconds(total)
+	doc := junitSuites{
+		Name: "xc-baseline", Tests: suite.Tests, Failures: suite.Failures, Errors: suite.Errors,
+		Skipped: suite.Skipped, Time: suite.Time, Suites: []junitSuite{suite},
+	}
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
+
+var csvHeader = []string{"主机名", "系统", "基线配置", "检查项ID", "检查项", "分类", "级别", "状态", "当前", "期望", "不符合项", "豁免"}
+
+// writeCSVReport writes one row per item. The UTF-8 BOM makes Excel and
+// WPS detect the encoding of the Chinese text.
+func writeCSVReport(out io.Writer, payload Output, host string) error {
+	if _, err := io.WriteString(out, "\ufeff"); err != nil {
+		return err
+	}
+	w := csv.NewWriter(out)
+	if err := w.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, item := range payload.Items {
+		failed := []string{}
+		for _, f := range failedFindings(item.Findings) {
+			failed = append(failed, findingLine(f))
+		}
+		waiver := ""
+		if w := item.Waiver; w != nil {
+			waiver = fmt.Sprintf("%s（审批人 %s，有效期至 %s）", w.Justification, w.Approver, w.Expires)
+			if w.Expired {
+				waiver = "已过期: " + waiver
+			}
+		}
+		row := []string{
+			host, payload.OS, payload.Profile.Name, item.ID, item.Name, item.Category,
+			labelOf(severityLabels, item.Severity), labelOf(statusLabels, item.Status),
+			item.Current, item.Expected, strings.Join(failed, "\n"), waiver,
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The history store is a JSON-lines file, one line per --check run.
+// When it reaches historyMaxBytes it is rotated to .1 ... .historyKeep.
+const (
+	defaultHistoryPath = "/var/lib/xc-baseline/history.jsonl"
+	historyMaxBytes    = 4 << 20
+	historyKeep        = 5
+	historyColumns     = 10
+)
+
+type HistoryEntry struct {
+	Time    time.Time     `json:"time"`
+	OS      string        `json:"os"`
+	Profile ProfileRef    `json:"profile"`
+	Summary Summary       `json:"summary"`
+	Items   []HistoryItem `json:"items"`
+}
+
+type HistoryItem struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// ItemTrend is one item across the recorded runs. Statuses is aligned to
+// HistoryReport.Runs ("" when the item was not checked in that run, e.g.
+// with --only); Current is the latest recorded status. FailingSince is the
+// first run of the current failing streak.
+type ItemTrend struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Current      string   `json:"current"`
+	Statuses     []string `json:"statuses"`
+	FirstFailed  string   `json:"first_failed,omitempty"`
+	FailingSince string   `json:"failing_since,omitempty"`
+	FailedRuns   int      `json:"failed_runs"`
+}
+
+type HistoryReport struct {
+	Path  string      `json:"path"`
+	Runs  []string    `json:"runs"`
+	Items []ItemTrend `json:"items"`
+}
+
+func appendHistory(path string, payload Output, now time.Time) error {
+	entry := HistoryEntry{Time: now, OS: payload.OS, Profile: payload.Profile, Summary: payload.Summary, Items: []HistoryItem{}}
+	for _, item := range payload.Items {
+		entry.Items = append(entry.Items, HistoryItem{ID: item.ID, Name: item.Name, Status: item.Status})
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if info, err := os.Stat(path); err == nil && info.Size()+int64(len(line)) >= historyMaxBytes {
+		if err := rotateHistory(path); err != nil {
+			return err
+		}
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+func rotateHistory(path string) error {
+	for i := historyKeep - 1; i >= 1; i-- {
+		older := fmt.Sprintf("%s.%d", path, i)
+		if _, err := os.Stat(older); err == nil {
+			if err := os.Rename(older, fmt.Sprintf("%s.%d", path, i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	return os.Rename(path, path+".1")
+}
+
+// readHistory returns the entries of path and its rotations, oldest first.
+func readHistory(path string) ([]HistoryEntry, error) {
+	files := []string{}
+	for i := historyKeep; i >= 1; i-- {
+		files = append(files, fmt.Sprintf("%s.%d", path, i))
+	}
+	files = append(files, path)
+	entries := []HistoryEntry{}
+	for _, name := range files {
+		file, err := os.Open(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), historyMaxBytes)
+		for n := 1; scanner.Scan(); n++ {
+			if strings.TrimSpace(scanner.Text()) == "" {
+				continue
+			}
+			var entry HistoryEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				file.Close()
+				return nil, fmt.Errorf("%s:%d: %v", name, n, err)
+			}
+			entries = append(entries, entry)
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s: 尚无历史记录（检查时加 --save-history）", path)
+	}
+	return entries, nil
+}
+
+func buildHistoryReport(path string, entries []HistoryEntry) HistoryReport {
+	report := HistoryReport{Path: path, Runs: []string{}, Items: []ItemTrend{}}
+	index := map[string]int{}
+	for run, entry := range entries {
+		when := entry.Time.Local().Format("2006-01-02 15:04")
+		report.Runs = append(report.Runs, when)
+		for _, item := range entry.Items {
+			i, ok := index[item.ID]
+			if !ok {
+				i = len(report.Items)
+				index[item.ID] = i
+				report.Items = append(report.Items, ItemTrend{ID: item.ID, Statuses: make([]string, len(entries))})
+			}
+			trend := &report.Items[i]
+			trend.Name = item.Name
+			trend.Current = item.Status
+			trend.Statuses[run] = item.Status
+			if item.Status != "fail" {
+				trend.FailingSince = ""
+				continue
+			}
+			trend.FailedRuns++
+			if trend.FirstFailed == "" {
+				trend.FirstFailed = when
+			}
+			if trend.FailingSince == "" {
+				trend.FailingSince = when
+			}
+		}
+	}
+	return report
+}
+
+var historySymbols = map[string]string{
+	"pass":           "✓",
+	"fail":           "✗",
+	"waived":         "W",
+	"manual":         "?",
+	"info":           "i",
+	"not_applicable": "-",
+	"timeout":        "T",
+	"error":          "E",
+	"":               " ",
+}
+
+func printHistoryReport(report HistoryReport, jsonOut bool, outputFile string) error {
+	var out io.Writer = os.Stdout
+	if outputFile != "" {
+		file, err := os.Create(outputFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	if jsonOut {
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	start := 0
+	if len(report.Runs) > historyColumns {
+		start = len(report.Runs) - historyColumns
+	}
+	fmt.Fprintf(out, "历史记录: %s（共%d次检查", report.Path, len(report.Runs))
+	if start > 0 {
+		fmt.Fprintf(out, "，显示最近%d次", historyColumns)
+	}
+	fmt.Fprintln(out, "）")
+	fmt.Fprintf(out, "时间范围: %s ~ %s\n", report.Runs[start], report.Runs[len(report.Runs)-1])
+	fmt.Fprintln(out, "图例: ✓通过 ✗不通过 W已豁免 ?需人工确认 i信息 -不适用 T超时 E错误（从旧到新）")
+	fmt.Fprintln(out, "============================================================")
+	for _, trend := range report.Items {
+		symbols := []string{}
+		for _, status := range trend.Statuses[start:] {
+			symbol, ok := historySymbols[status]
+			if !ok {
+				symbol = status
+			}
+			symbols = append(symbols, symbol)
+		}
+		line := strings.Join(symbols, " ")
+		fmt.Fprintf(out, "[%s] %s\n  %s  当前: %s\n", trend.ID, trend.Name, line, valueOrNA(trend.Current))
+		if trend.FailingSince != "" {
+			fmt.Fprintf(out, "  %s\n", colorize("red", "自 "+trend.FailingSince+" 起持续不通过"))
+		}
+		if trend.FirstFailed != "" {
+			fmt.Fprintf(out, "  首次不通过: %s（累计%d次）\n", trend.FirstFailed, trend.FailedRuns)
+		}
+	}
+	fmt.Fprintln(out, "============================================================")
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"html/template"
+	"io"
+	"strings"
+)
+
+// The HTML report is a single file with inline CSS and no scripts or
+// external assets, so it opens on offline hosts and can be handed over as is.
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"join":   strings.Join,
+	"status": func(s string) string { return strings.ReplaceAll(s, "_", "-") },
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>信创基线检查报告 - {{.Host.Hostname}}</title>
+<style>
+body { font-family: "Noto Sans CJK SC", "Source Han Sans SC", "WenQuanYi Micro Hei", "Microsoft YaHei", sans-serif; margin: 0; background: #f4f5f7; color: #222; }
+main { max-width: 1000px; margin: 0 auto; padding: 24px; }
+h1 { font-size: 24px; margin: 0 0 16px; }
+h2 { font-size: 18px; margin: 28px 0 12px; }
+section, article { background: #fff; border-radius: 6px; padding: 16px 20px; margin-bottom: 12px; box-shadow: 0 1px 2px rgba(0,0,0,.08); }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 6px 8px; border-bottom: 1px solid #eee; vertical-align: top; font-size: 14px; }
+th { width: 120px; color: #555; font-weight: normal; }
+.summary { display: flex; gap: 24px; align-items: center; flex-wrap: wrap; }
+.score { font-size: 44px; font-weight: bold; }
+.grade { font-size: 20px; color: #555; }
+.counts span { display: inline-block; margin: 2px 6px 2px 0; }
+.badge { display: inline-block; padding: 2px 8px; border-radius: 10px; font-size: 13px; color: #fff; background: #888; }
+.badge.pass { background: #2e7d32; } .badge.fail { background: #c62828; }
+.badge.waived, .badge.timeout, .badge.error { background: #e08a00; } .badge.manual { background: #1565c0; }
+article { border-left: 5px solid #888; }
+article.pass { border-left-color: #2e7d32; } article.fail { border-left-color: #c62828; }
+article.waived, article.timeout, article.error { border-left-color: #e08a00; } article.manual { border-left-color: #1565c0; }
+article h3 { font-size: 16px; margin: 0 0 8px; display: flex; gap: 8px; align-items: center; }
+article h3 .id { color: #888; font-weight: normal; font-size: 13px; }
+.evidence { font-family: monospace; font-size: 13px; white-space: pre-wrap; word-break: break-all; }
+.findings li { margin: 2px 0; }
+.hint { background: #fff4e5; border-radius: 4px; padding: 8px 10px; margin-top: 8px; }
+.waiver { background: #eef3fb; border-radius: 4px; padding: 8px 10px; margin-top: 8px; }
+footer { color: #888; font-size: 12px; text-align: center; margin: 24px 0; }
+@media print { body { background: #fff; } section, article { box-shadow: none; border: 1px solid #ddd; } article { break-inside: avoid; } }
+</style>
+</head>
+<body>
+<main>
+<h1>信创基线检查报告</h1>
+<section>
+<table>
+<tr><th>主机名</th><td>{{or .Host.Hostname "未知"}}</td></tr>
+{{- if .Host.Addresses}}
+<tr><th>IP地址</th><td>{{join .Host.Addresses ", "}}</td></tr>
+{{- end}}
+<tr><th>操作系统</th><td>{{.Host.OS}}</td></tr>
+{{- if .Host.Kernel}}
+<tr><th>内核版本</th><td>{{.Host.Kernel}}</td></tr>
+{{- end}}
+{{- if .Host.Root}}
+<tr><th>离线扫描</th><td>{{.Host.Root}}</td></tr>
+{{- end}}
+<tr><th>基线配置</th><td>{{.Output.Profile.Name}} ({{.Output.Profile.Hash}})</td></tr>
+<tr><th>检查时间</th><td>{{.Host.CheckedAt.Format "2006-01-02 15:04:05"}}</td></tr>
+{{- if .Inspector}}
+<tr><th>检查人</th><td>{{.Inspector}}</td></tr>
+{{- end}}
+</table>
+</section>
+<section class="summary">
+{{- with .Output.Summary}}
+{{- if gt .Total 0}}
+<div><div class="score">{{printf "%.1f" .Score}}</div><div class="grade">等级 {{.Grade}}（加权 {{.Earned}}/{{.Total}}）</div></div>
+{{- else}}
+<div class="grade">无可评分检查项</div>
+{{- end}}
+{{- end}}
+<div class="counts">
+{{- range .Counts}}
+<span><span class="badge {{status .Status}}">{{.Label}}</span> {{.Count}}</span>
+{{- end}}
+</div>
+</section>
+<h2>检查项</h2>
+{{- range .Items}}
+<article class="{{status .Status}}">
+<h3><span class="badge {{status .Status}}">{{.StatusLabel}}</span>{{.Name}}<span class="id">{{.ID}} · 级别{{.SeverityLabel}}</span></h3>
+<table>
+{{- if .Desc}}
+<tr><th>说明</th><td>{{.Desc}}</td></tr>
+{{- end}}
+<tr><th>当前</th><td class="evidence">{{.Current}}</td></tr>
+<tr><th>期望</th><td>{{.Expected}}</td></tr>
+{{- if .Failed}}
+<tr><th>不符合项</th><td><ul class="findings">
+{{- range .Failed}}
+<li>{{or .Label .Key}}: {{.Observed}}{{if .Expected}}（期望 {{.Expected}}）{{end}}{{with .Source}} <span class="evidence">{{.String}}</span>{{end}}</li>
+{{- end}}
+</ul></td></tr>
+{{- end}}
+</table>
+{{- with .Waiver}}
+<div class="waiver">{{if .Expired}}豁免（已过期）{{else}}豁免{{end}}: {{.Justification}}（审批人 {{.Approver}}，有效期至 {{.Expires}}）{{if .Findings}} 范围: {{join .Findings ", "}}{{end}}</div>
+{{- end}}
+{{- if .Hint}}
+<div class="hint">修复指引: {{.Hint}}</div>
+{{- end}}
+</article>
+{{- end}}
+<footer>xc-baseline-go 生成</footer>
+</main>
+</body>
+</html>
+`))
+
+func writeHTMLReport(out io.Writer, view ReportView) error {
+	return htmlReport.Execute(out, view)
+}
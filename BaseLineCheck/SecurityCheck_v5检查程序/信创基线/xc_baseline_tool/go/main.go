@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -8,25 +10,19 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
+	"syscall"
+	"time"
 )
 
-type Item struct {
-	ID        string
-	Name      string
-	Desc      string
-	Expected  string
-	CanApply  bool
-	CheckFunc func() Result
-	ApplyFunc func() error
-}
-
 type Result struct {
-	Status  string `json:"status"`
-	Current string `json:"current"`
+	Status   string    `json:"status"`
+	Current  string    `json:"current"`
+	Findings []Finding `json:"findings,omitempty"`
 }
 
 type OutputItem struct {
@@ -34,14 +30,24 @@ type OutputItem struct {
 	Name     string `json:"name"`
 	Desc     string `json:"description"`
 	Expected string `json:"expected"`
+	Category string `json:"category"`
+	Severity string `json:"severity"`
 	CanApply bool   `json:"can_apply"`
 	Status   string `json:"status"`
 	Current  string `json:"current"`
+
+	Findings   []Finding  `json:"findings"`
+	Waiver     *WaiverRef `json:"waiver,omitempty"`
+	DurationMS int64      `json:"duration_ms"`
 }
 
 type Output struct {
-	OS    string       `json:"os"`
-	Items []OutputItem `json:"items"`
+	OS         string       `json:"os"`
+	Root       string       `json:"root,omitempty"`
+	Profile    ProfileRef   `json:"profile"`
+	Summary    Summary      `json:"summary"`
+	Items      []OutputItem `json:"items"`
+	DurationMS int64        `json:"duration_ms,omitempty"`
 }
 
 type OSInfo struct {
@@ -54,192 +60,550 @@ type OSInfo struct {
 
 func main() {
 	var (
-		flagCheck    = flag.Bool("check", false, "执行全部基线检查")
-		flagApply    = flag.String("apply", "", "应用指定基线项")
-		flagApplyAll = flag.Bool("apply-all", false, "应用所有可设置项")
-		flagCheckFix = flag.Bool("check-fix", false, "检查后按提示修复失败项")
-		flagList     = flag.Bool("list", false, "列出基线项")
-		flagJSON     = flag.Bool("json", false, "JSON输出")
-		flagOutput   = flag.String("output", "", "输出到文件")
+		flagCheck     = flag.Bool("check", false, "执行全部基线检查")
+		flagApply     = flag.String("apply", "", "应用指定基线项")
+		flagApplyAll  = flag.Bool("apply-all", false, "应用所有可设置项")
+		flagCheckFix  = flag.Bool("check-fix", false, "检查后按提示修复失败项")
+		flagList      = flag.Bool("list", false, "列出基线项")
+		flagJSON      = flag.Bool("json", false, "已弃用，同 --format json")
+		flagFormat    = flag.String("format", "", "输出格式，逗号分隔可同时输出多种: text/json/html/docx/xlsx/junit/csv/xccdf/prom（可写成 格式=文件）")
+		flagInspect   = flag.String("inspector", "", "检查人（写入 html/docx 检查报告）")
+		flagOutput    = flag.String("output", "", "输出到文件")
+		flagPromFile  = flag.String("prom-textfile", "", "另写一份 Prometheus 指标文件（node_exporter textfile，原子替换）")
+		flagProfile   = flag.String("profile", "", "基线配置文件（JSON/YAML）")
+		flagRoot      = flag.String("root", "", "离线扫描已挂载的根文件系统目录")
+		flagRecord    = flag.String("record", "", "检查时录制命令与文件读取到目录")
+		flagReplay    = flag.String("replay", "", "基于录制目录重放检查")
+		flagJobs      = flag.Int("jobs", defaultJobs, "并发检查数")
+		flagItemTO    = flag.Duration("item-timeout", defaultItemTimeout, "单项检查超时")
+		flagTimeout   = flag.Duration("timeout", 0, "整体检查超时（0为不限制）")
+		flagFailOn    = flag.String("fail-on", "low", "达到该严重级别(high/medium/low)的失败项才以非0退出")
+		flagOnly      = flag.String("only", "", "仅执行指定检查项（逗号分隔ID）")
+		flagSkip      = flag.String("skip", "", "跳过指定检查项（逗号分隔ID）")
+		flagTags      = flag.String("tags", "", "仅执行指定分类（逗号分隔，如 network,account）")
+		flagDryRun    = flag.Bool("dry-run", false, "仅预演修复，列出计划变更而不修改系统")
+		flagRollback  = flag.String("rollback", "", "按运行ID恢复修复前的文件")
+		flagApproved  = flag.String("approved-plan", "", "仅当修复计划摘要与之一致时执行（PAM变更必需）")
+		flagEmitFix   = flag.String("emit-fix-script", "", "检查后为失败项生成修复脚本（不修改系统）")
+		flagDiff      = flag.String("diff", "", "对比两次检查结果: --diff old.json new.json")
+		flagHistory   = flag.Bool("history", false, "查看历史检查记录与各项不通过起始时间")
+		flagSaveHist  = flag.Bool("save-history", false, "检查结果追加到本机历史记录")
+		flagHistFile  = flag.String("history-file", defaultHistoryPath, "历史记录文件")
+		flagUpload    = flag.String("upload", "", "检查结果上传到汇总服务器（如 http://172.16.1.20:8000/log）")
+		flagFailCache = flag.String("fail-cache", defaultUploadCache, "上传失败的结果缓存文件")
+		flagSignKey   = flag.String("sign-key", "", "结果签名私钥文件（默认使用编译时内置密钥）")
+		flagVerify    = flag.String("verify", "", "校验结果文件签名: --verify result.json")
+		flagPubKey    = flag.String("pubkey", "", "校验签名的公钥文件（逗号分隔多个）")
+		flagSigFile   = flag.String("sig", "", "签名文件（默认为 <结果文件>.sig）")
+		flagGenKey    = flag.String("gen-key", "", "生成签名密钥对(sm2/ed25519)，--output 指定文件名前缀")
+		flagInstall   = flag.Bool("install-agent", false, "安装systemd定时检查")
+		flagUninst    = flag.Bool("uninstall-agent", false, "卸载定时检查")
+		flagSchedule  = flag.String("schedule", "daily", "定时检查周期（systemd OnCalendar，如 daily、Mon *-*-* 02:00）")
+		flagJitter    = flag.Duration("jitter", 30*time.Minute, "定时检查随机延迟上限")
+		flagWaivers   = flag.String("waivers", "", "风险豁免文件（JSON/YAML）")
+		flagOVAL      = flag.String("oval", "", "加载OVAL定义文件作为附加检查项（逗号分隔多个）")
+		flagAnsible   = flag.String("emit-ansible", "", "为失败项生成Ansible playbook目录")
+		flagResult    = flag.String("result", "", "基于已有的 --check --format json 结果文件生成（配合 --emit-ansible）")
 	)
-	flag.Parse()
+	// Keep flag errors out of the exit-code range reserved for check verdicts.
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(exitPass)
+		}
+		os.Exit(exitError)
+	}
+
+	if *flagJSON {
+		fmt.Fprintln(os.Stderr, "提示: --json 已弃用，请改用 --format json")
+	}
+
+	if *flagDiff != "" {
+		// The new result is positional; flags may follow it.
+		newPath := flag.Arg(0)
+		if newPath == "" || flag.CommandLine.Parse(flag.Args()[1:]) != nil || flag.NArg() != 0 {
+			fmt.Fprintln(os.Stderr, "用法: --diff old.json new.json [--format text|json] [--output FILE]")
+			os.Exit(exitError)
+		}
+		format, err := parseFormat(*flagFormat, *flagJSON, "text", "json")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(exitError)
+		}
+		before, err := loadOutput(*flagDiff)
+		if err == nil {
+			var after Output
+			after, err = loadOutput(newPath)
+			if err == nil {
+				err = printDriftReport(diffOutputs(*flagDiff, newPath, before, after), format == "json", *flagOutput)
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "结果对比失败: "+err.Error())
+			os.Exit(exitError)
+		}
+		return
+	}
+
+	if *flagHistory {
+		format, err := parseFormat(*flagFormat, *flagJSON, "text", "json")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(exitError)
+		}
+		entries, err := readHistory(*flagHistFile)
+		if err == nil {
+			err = printHistoryReport(buildHistoryReport(*flagHistFile, entries), format == "json", *flagOutput)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "历史记录读取失败: "+err.Error())
+			os.Exit(exitError)
+		}
+		return
+	}
+
+	if *flagGenKey != "" {
+		if *flagOutput == "" {
+			fmt.Fprintln(os.Stderr, "用法: --gen-key sm2|ed25519 --output PREFIX")
+			os.Exit(exitError)
+		}
+		key, err := generateSignKey(*flagGenKey)
+		var files []string
+		if err == nil {
+			files, err = writeKeyPair(*flagOutput, key)
+		}
+		for _, path := range files {
+			fmt.Println("已生成: " + path)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "密钥生成失败: "+err.Error())
+			os.Exit(exitError)
+		}
+		fmt.Printf("密钥ID: %s（私钥用于 --sign-key 或编译时 XC_SIGN_KEY，公钥用于 --verify --pubkey）\n", key.Public().KeyID())
+		return
+	}
+
+	if *flagVerify != "" {
+		keys := []*VerifyKey{}
+		for _, path := range splitList(*flagPubKey) {
+			key, err := loadVerifyKey(path)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "公钥加载失败: "+err.Error())
+				os.Exit(exitError)
+			}
+			keys = append(keys, key)
+		}
+		if len(keys) == 0 {
+			key, err := resolveSignKey("")
+			if err != nil || key == nil {
+				fmt.Fprintln(os.Stderr, "请用 --pubkey 指定公钥文件")
+				os.Exit(exitError)
+			}
+			keys = append(keys, key.Public())
+		}
+		sigPath := *flagSigFile
+		if sigPath == "" {
+			sigPath = *flagVerify + sigSuffix
+		}
+		keyID, err := verifyResultFile(*flagVerify, sigPath, keys)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, colorize("red", "签名校验失败: "+err.Error()))
+			os.Exit(exitBadSignature)
+		}
+		fmt.Println(colorize("green", fmt.Sprintf("签名有效: %s（密钥 %s）", *flagVerify, keyID)))
+		return
+	}
 
 	if runtime.GOOS != "linux" {
 		fmt.Fprintln(os.Stderr, "仅支持Linux系统运行")
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
-	items := buildItems()
+	if *flagUpload != "" {
+		if err := validateUploadURL(*flagUpload); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(exitError)
+		}
+	}
 
-	if *flagList {
-		for _, item := range items {
-			fmt.Printf("%s\t%s\n", item.ID, item.Name)
+	// OVAL items must be registered before profiles, waivers and --only
+	// refer to them.
+	if *flagOVAL != "" {
+		if err := registerOVAL(splitList(*flagOVAL)); err != nil {
+			fmt.Fprintln(os.Stderr, "OVAL 文件加载失败: "+err.Error())
+			os.Exit(exitError)
 		}
-		return
 	}
 
-	if *flagCheck {
-		runCheck(items, *flagJSON, *flagOutput)
+	if *flagInstall || *flagUninst {
+		var files []string
+		var err error
+		if *flagInstall {
+			files, err = installAgent(context.Background(), AgentOptions{Schedule: *flagSchedule, Jitter: *flagJitter, Profile: *flagProfile, Waivers: *flagWaivers, OVAL: *flagOVAL, PromFile: *flagPromFile, Upload: *flagUpload, SignKey: *flagSignKey})
+		} else {
+			files, err = uninstallAgent(context.Background())
+		}
+		action := "已安装"
+		if *flagUninst {
+			action = "已删除"
+		}
+		for _, path := range files {
+			fmt.Println(action + ": " + path)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(exitError)
+		}
+		if *flagInstall {
+			fmt.Printf("定时检查已启用（%s，随机延迟%s）；结果: %s，历史: xc-baseline-go --history\n", *flagSchedule, *flagJitter, agentResultPath)
+		} else {
+			fmt.Println("定时检查已卸载（检查结果与历史记录保留在 /var/lib/xc-baseline）")
+		}
 		return
 	}
 
-	if *flagApplyAll || *flagCheckFix {
-		fmt.Fprintln(os.Stderr, "自动修复已禁用")
+	failOnRank, err := parseFailOn(*flagFailOn)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(exitError)
+	}
+	profile := defaultProfile()
+	if *flagProfile != "" {
+		loaded, err := loadProfile(*flagProfile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "基线配置加载失败: "+err.Error())
+			os.Exit(exitError)
+		}
+		profile = loaded
+	}
+	if *flagRoot != "" {
+		if info, err := os.Stat(*flagRoot); err != nil || !info.IsDir() {
+			fmt.Fprintln(os.Stderr, "根文件系统目录不可用: "+*flagRoot)
+			os.Exit(exitError)
+		}
+	}
+	if *flagReplay != "" && *flagRoot != "" {
+		fmt.Fprintln(os.Stderr, "--replay 不能与 --root 同时使用")
+		os.Exit(exitError)
+	}
+	var sys System = newHostSystem(*flagRoot)
+	var replay *replaySystem
+	if *flagReplay != "" {
+		loaded, err := loadReplaySystem(*flagReplay)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "录制包加载失败: "+err.Error())
+			os.Exit(exitError)
+		}
+		replay = loaded
+		sys = replay
+	}
+	var recorder *recordingSystem
+	if *flagRecord != "" {
+		created, err := newRecordingSystem(sys, *flagRecord)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "录制目录不可用: "+err.Error())
+			os.Exit(exitError)
+		}
+		recorder = created
+		sys = recorder
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *flagTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *flagTimeout)
+		defer cancel()
+	}
+	rc := newRunContext(ctx, profile, sys)
+	rc.Jobs = *flagJobs
+	if *flagWaivers != "" {
+		waivers, err := loadWaivers(*flagWaivers)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "豁免文件加载失败: "+err.Error())
+			os.Exit(exitError)
+		}
+		rc.Waivers = waivers
+	}
+	rc.ItemTimeout = *flagItemTO
+	if replay != nil {
+		rc.IsRoot = replay.manifest.IsRoot
+	}
+	selection := Selection{Only: splitList(*flagOnly), Skip: splitList(*flagSkip), Tags: splitList(*flagTags)}
+	checkers, err := selection.apply(enabledCheckers(rc, registeredCheckers()))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(exitError)
+	}
+
+	if *flagList {
+		for _, c := range checkers {
+			meta := c.Meta()
+			fmt.Printf("%s\t%s\t%s\t%s\n", meta.ID, meta.Name, meta.Category, meta.Severity)
+		}
 		return
 	}
 
-	if *flagApply != "" {
-		fmt.Fprintln(os.Stderr, "自动修复已禁用")
+	if *flagAnsible != "" && *flagResult != "" {
+		var payload Output
+		if err := readJSONFile(*flagResult, &payload); err != nil {
+			fmt.Fprintln(os.Stderr, "结果文件读取失败: "+err.Error())
+			os.Exit(exitError)
+		}
+		if payload.Profile.Hash != profile.ref().Hash {
+			fmt.Fprintf(os.Stderr, "结果文件基于基线配置 %s (%s)，请使用相同的 --profile\n", payload.Profile.Name, payload.Profile.Hash)
+			os.Exit(exitError)
+		}
+		if err := emitAnsible(profile, payload, *flagAnsible); err != nil {
+			fmt.Fprintln(os.Stderr, "Ansible playbook 生成失败: "+err.Error())
+			os.Exit(exitError)
+		}
+		fmt.Printf("Ansible playbook 已生成: %s\n", filepath.Join(*flagAnsible, "site.yml"))
 		return
 	}
 
-	printHelp()
-}
+	if *flagCheck || replay != nil || *flagEmitFix != "" || *flagAnsible != "" {
+		if *flagSaveHist && (replay != nil || rc.scanRoot() != "") {
+			fmt.Fprintln(os.Stderr, "--save-history 仅用于本机检查，不能与 --root/--replay 同时使用")
+			os.Exit(exitError)
+		}
+		targets, err := parseOutputTargets(*flagFormat, *flagJSON, *flagOutput, *flagPromFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(exitError)
+		}
+		resultPath := jsonTargetPath(targets)
+		rc.Inspector = *flagInspect
+		signKey, err := resolveSignKey(*flagSignKey)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "签名密钥加载失败: "+err.Error())
+			os.Exit(exitError)
+		}
+		if *flagSignKey != "" && resultPath == "" {
+			fmt.Fprintln(os.Stderr, "--sign-key 需将 json 结果输出到文件（--format json --output FILE）")
+			os.Exit(exitError)
+		}
+		if *flagUpload != "" && (replay != nil || rc.scanRoot() != "") {
+			// The server files rows under the sender's IP.
+			fmt.Fprintln(os.Stderr, "--upload 仅用于本机检查，不能与 --root/--replay 同时使用")
+			os.Exit(exitError)
+		}
+		payload := runCheck(rc, checkers, targets)
+		if signKey != nil && resultPath != "" {
+			if err := signResultFile(resultPath, signKey); err != nil {
+				fmt.Fprintln(os.Stderr, "结果签名失败: "+err.Error())
+				os.Exit(exitError)
+			}
+			fmt.Fprintf(os.Stderr, "结果已签名: %s%s\n", resultPath, sigSuffix)
+		}
+		if *flagSaveHist {
+			if err := appendHistory(*flagHistFile, payload, time.Now()); err != nil {
+				fmt.Fprintln(os.Stderr, "历史记录保存失败: "+err.Error())
+			}
+		}
+		if *flagUpload != "" {
+			body, err := json.Marshal(uploadRows(payload, readHostname(rc), time.Now()))
+			if err == nil {
+				var sent int
+				sent, err = uploadResult(*flagUpload, *flagFailCache, body)
+				if sent > 1 {
+					fmt.Fprintf(os.Stderr, "已补传%d份缓存结果\n", sent-1)
+				}
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "结果上传失败: "+err.Error())
+			} else {
+				fmt.Fprintln(os.Stderr, "检查结果已上传: "+*flagUpload)
+			}
+		}
+		if *flagAnsible != "" {
+			if err := emitAnsible(profile, payload, *flagAnsible); err != nil {
+				fmt.Fprintln(os.Stderr, "Ansible playbook 生成失败: "+err.Error())
+				os.Exit(exitError)
+			}
+			fmt.Fprintf(os.Stderr, "Ansible playbook 已生成: %s\n", filepath.Join(*flagAnsible, "site.yml"))
+		}
+		if *flagEmitFix != "" {
+			if err := emitFixScript(rc, payload, *flagEmitFix); err != nil {
+				fmt.Fprintln(os.Stderr, "修复脚本生成失败: "+err.Error())
+				os.Exit(exitError)
+			}
+			fmt.Fprintf(os.Stderr, "修复脚本已生成: %s（执行前请审阅）\n", *flagEmitFix)
+		}
+		if recorder != nil {
+			if err := recorder.save(rc, payload); err != nil {
+				fmt.Fprintln(os.Stderr, "录制包保存失败: "+err.Error())
+				os.Exit(exitError)
+			}
+			fmt.Fprintf(os.Stderr, "录制包已保存: %s\n", *flagRecord)
+		}
+		if replay != nil {
+			diffs, err := replay.compareRecorded(payload.Items)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "录制结果读取失败: "+err.Error())
+			}
+			for _, diff := range diffs {
+				fmt.Fprintln(os.Stderr, "与录制结果不一致: "+diff)
+			}
+		}
+		os.Exit(exitCodeFor(payload.Items, failOnRank))
+	}
 
-func printHelp() {
-	fmt.Println("用法:")
-	fmt.Println("  xc-baseline-go --check [--json] [--output FILE]")
-	fmt.Println("  xc-baseline-go --apply ITEM_ID  (已禁用)")
-	fmt.Println("  xc-baseline-go --apply-all      (已禁用)")
-	fmt.Println("  xc-baseline-go --check-fix      (已禁用)")
-	fmt.Println("  xc-baseline-go --list")
-}
-
-func buildItems() []Item {
-	// Baseline catalog: wire checks + auto-fix handlers here.
-	return []Item{
-		{
-			ID:        "ftp_service",
-			Name:      "FTP服务禁用",
-			Desc:      "检查是否存在FTP服务运行，基线要求禁用。",
-			Expected:  "FTP服务未运行且已禁用",
-			CanApply:  false,
-			CheckFunc: checkFTPService,
-		},
-		{
-			ID:        "nic_info",
-			Name:      "网卡信息检查",
-			Desc:      "展示当前网卡与IP信息。",
-			Expected:  "仅展示信息",
-			CanApply:  false,
-			CheckFunc: checkNICInfo,
-		},
-		{
-			ID:        "risky_ports",
-			Name:      "高危端口状态检测",
-			Desc:      "检测22/23/135/137/138/139/445/455/3389/4899监听状态。",
-			Expected:  "无高危端口监听",
-			CanApply:  false,
-			CheckFunc: checkRiskyPorts,
-		},
-		{
-			ID:        "usb_autoplay",
-			Name:      "U盘自动播放",
-			Desc:      "检查桌面环境自动挂载/自动打开策略。",
-			Expected:  "自动挂载/自动打开关闭",
-			CanApply:  false,
-			CheckFunc: checkUSBAutoplay,
-		},
-		{
-			ID:        "ipv6_disabled",
-			Name:      "IPv6禁用状态",
-			Desc:      "检查IPv6禁用是否生效。",
-			Expected:  "IPv6已禁用",
-			CanApply:  false,
-			CheckFunc: checkIPv6Disabled,
-		},
-		{
-			ID:        "patch_updates",
-			Name:      "高危漏洞修复",
-			Desc:      "检测系统更新状态（离线环境基于本地缓存判断）。",
-			Expected:  "无待更新补丁",
-			CanApply:  false,
-			CheckFunc: checkPatchUpdates,
-		},
-		{
-			ID:        "password_policy",
-			Name:      "密码策略",
-			Desc:      "检查密码最小长度/复杂度/有效期策略。",
-			Expected:  "最小长度>=10，最短1天，最长90天，复杂度>=4类，失败锁定",
-			CanApply:  false,
-			CheckFunc: checkPasswordPolicy,
-		},
-		{
-			ID:        "lock_screen",
-			Name:      "锁屏策略",
-			Desc:      "检查锁屏开启与自动锁定时间。",
-			Expected:  "锁屏启用，空闲15分钟内锁定",
-			CanApply:  false,
-			CheckFunc: checkLockScreen,
-		},
-		{
-			ID:        "audit_rules",
-			Name:      "日志审计规则",
-			Desc:      "检查审计服务与规则配置。",
-			Expected:  "auditd运行且已加载规则",
-			CanApply:  false,
-			CheckFunc: checkAuditRules,
-		},
-	}
-}
-
-func runCheck(items []Item, jsonOut bool, outputFile string) {
-	results := make([]OutputItem, 0, len(items))
-	for _, item := range items {
-		res := item.CheckFunc()
-		results = append(results, OutputItem{
-			ID:       item.ID,
-			Name:     item.Name,
-			Desc:     item.Desc,
-			Expected: item.Expected,
-			CanApply: item.CanApply,
-			Status:   res.Status,
-			Current:  res.Current,
-		})
+	if *flagRollback != "" {
+		restored, err := rollbackRun(*flagRollback)
+		for _, path := range restored {
+			fmt.Println("已恢复: " + path)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "回滚失败: "+err.Error())
+			os.Exit(exitError)
+		}
+		fmt.Printf("运行 %s 已回滚（已执行的命令不会撤销）\n", *flagRollback)
+		return
 	}
 
-	var out io.Writer = os.Stdout
-	if outputFile != "" {
-		file, err := os.Create(outputFile)
+	if *flagApplyAll || *flagCheckFix || *flagApply != "" {
+		format, err := parseFormat(*flagFormat, *flagJSON, "text", "json")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(exitError)
+		}
+		if replay != nil || recorder != nil || rc.scanRoot() != "" {
+			fmt.Fprintln(os.Stderr, "自动修复仅支持在线主机，不能与 --root/--record/--replay 同时使用")
+			os.Exit(exitError)
+		}
+		if !*flagDryRun && !rc.IsRoot {
+			fmt.Fprintln(os.Stderr, "自动修复需要root权限（可先用 --dry-run 预演）")
+			os.Exit(exitError)
+		}
+		targets := checkers
+		if *flagApply != "" {
+			c, ok := findChecker(*flagApply)
+			if !ok {
+				fmt.Fprintln(os.Stderr, "未知检查项: "+*flagApply)
+				os.Exit(exitError)
+			}
+			targets = []Checker{c}
+		}
+		var before []OutputItem
+		if *flagCheckFix {
+			before = runCheck(rc, targets, []outputTarget{{Format: "text"}}).Items
+		} else {
+			before = collectResults(rc, targets)
+		}
+		if !*flagDryRun {
+			if err := approvePlan(rc, targets, before, *flagApproved); err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(exitError)
+			}
+		}
+		tx, err := newTx(*flagDryRun)
 		if err != nil {
+			fmt.Fprintln(os.Stderr, "修复日志目录不可用: "+err.Error())
+			os.Exit(exitError)
+		}
+		rc.Tx = tx
+		if tx.DryRun {
+			rc.Sys = txSystem{System: rc.Sys, tx: tx}
+		}
+		var confirm func(OutputItem) bool
+		if *flagCheckFix && !tx.DryRun {
+			confirm = promptFix(bufio.NewReader(os.Stdin))
+		}
+		report := remediate(rc, targets, before, confirm)
+		if err := printApplyReport(report, format == "json", *flagOutput); err != nil {
 			fmt.Fprintln(os.Stderr, err.Error())
-			os.Exit(1)
+			os.Exit(exitError)
 		}
-		defer file.Close()
-		out = file
+		os.Exit(report.exitCode())
 	}
 
-	if jsonOut {
-		// Stable machine-readable output for batch collection.
-		payload := Output{OS: readOSRelease(), Items: results}
-		enc := json.NewEncoder(out)
-		enc.SetEscapeHTML(false)
-		enc.SetIndent("", "  ")
-		if err := enc.Encode(payload); err != nil {
+	printHelp()
+}
+
+func printHelp() {
+	fmt.Println("用法:")
+	fmt.Println("  xc-baseline-go --check [--profile FILE] [--root DIR] [--record DIR] [--output FILE]")
+	fmt.Println("                 [--format FORMAT[=FILE],...]（text/json/html/docx/xlsx/junit/csv/xccdf/prom，多种格式各写一个文件）")
+	fmt.Println("                 [--jobs N] [--item-timeout 60s] [--timeout 5m] [--fail-on high|medium|low]")
+	fmt.Println("                 [--only ID,...] [--skip ID,...] [--tags CATEGORY,...] [--waivers FILE]")
+	fmt.Println("                 [--oval FILE,...]（OVAL 定义作为附加检查项）")
+	fmt.Println("                 [--save-history] [--history-file FILE] [--upload URL] [--fail-cache FILE] [--inspector NAME]")
+	fmt.Println("                 [--sign-key FILE]（json 结果输出到文件时生成 <结果文件>.sig）")
+	fmt.Println("                 [--prom-textfile FILE]（node_exporter textfile 指标）")
+	fmt.Println("  xc-baseline-go --check --emit-fix-script FILE [--profile FILE] [--root DIR]")
+	fmt.Println("  xc-baseline-go --emit-ansible DIR [--result result.json] [--profile FILE]")
+	fmt.Println("  xc-baseline-go --gen-key sm2|ed25519 --output PREFIX")
+	fmt.Println("  xc-baseline-go --verify result.json [--pubkey site.pub[,...]] [--sig result.json.sig]")
+	fmt.Println("  xc-baseline-go --install-agent [--schedule daily] [--jitter 30m] [--profile FILE] [--waivers FILE] [--oval FILE,...] [--upload URL] [--sign-key FILE] [--prom-textfile FILE]")
+	fmt.Println("  xc-baseline-go --uninstall-agent")
+	fmt.Println("  xc-baseline-go --history [--history-file FILE] [--format text|json]")
+	fmt.Println("  xc-baseline-go --diff old.json new.json [--format text|json] [--output FILE]")
+	fmt.Println("  xc-baseline-go --replay DIR [--profile FILE] [--oval FILE,...] [--format FORMAT,...] [--output FILE]")
+	fmt.Println("  xc-baseline-go --apply ITEM_ID [--dry-run] [--approved-plan HASH] [--format text|json]")
+	fmt.Println("  xc-baseline-go --apply-all [--dry-run] [--approved-plan HASH] [--format text|json]")
+	fmt.Println("  xc-baseline-go --check-fix [--dry-run] [--approved-plan HASH]")
+	fmt.Println("  xc-baseline-go --rollback RUN_ID")
+	fmt.Println("  xc-baseline-go --list [--only ID,...] [--skip ID,...] [--tags CATEGORY,...] [--oval FILE,...]")
+	fmt.Println("退出码: 0 全部通过  1 存在失败项  2 仅有需人工确认/未知项  3 执行错误  4 签名无效（--verify）")
+}
+
+func runCheck(rc *RunContext, checkers []Checker, targets []outputTarget) Output {
+	start := time.Now()
+	results := collectResults(rc, checkers)
+	payload := Output{OS: readOSRelease(rc), Root: rc.scanRoot(), Profile: rc.Profile.ref(), Summary: summarize(rc.Profile, results), Items: results}
+	payload.DurationMS = time.Since(start).Milliseconds()
+	for _, target := range targets {
+		if err := writeOutput(rc, payload, target); err != nil {
 			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(exitError)
 		}
-		return
 	}
+	return payload
+}
 
-	fmt.Fprintf(out, "系统识别: %s\n", readOSRelease())
+func writeTextReport(out io.Writer, rc *RunContext, payload Output) {
+	fmt.Fprintf(out, "系统识别: %s\n", readOSRelease(rc))
+	if root := rc.scanRoot(); root != "" {
+		fmt.Fprintf(out, "离线扫描: %s\n", root)
+	}
+	fmt.Fprintf(out, "基线配置: %s (%s)\n", rc.Profile.Name, rc.Profile.ref().Hash)
 	fmt.Fprintln(out, "============================================================")
 	checkedNames := []string{}
 	manualNames := []string{}
-	for _, item := range results {
+	for _, item := range payload.Items {
 		checkedNames = append(checkedNames, item.Name)
-		if !item.CanApply || item.Status == "manual" {
+		// Timed-out or failed checks gave no verdict and need a person too.
+		if item.Status == "manual" || item.Status == "timeout" || item.Status == "error" {
 			manualNames = append(manualNames, item.Name)
 		}
 		name := item.Name
-		if item.Status == "fail" {
+		switch item.Status {
+		case "fail":
 			name = colorize("red", name)
+		case "timeout", "error", "waived":
+			name = colorize("yellow", name)
 		}
 		fmt.Fprintf(out, "[%s] %s\n", item.ID, name)
 		fmt.Fprintf(out, "状态: %s\n", item.Status)
+		fmt.Fprintf(out, "级别: %s\n", item.Severity)
 		fmt.Fprintf(out, "当前: %s\n", item.Current)
 		fmt.Fprintf(out, "期望: %s\n", item.Expected)
+		for _, f := range failedFindings(item.Findings) {
+			fmt.Fprintln(out, "  - "+findingLine(f))
+		}
+		if w := item.Waiver; w != nil {
+			state := "豁免"
+			if w.Expired {
+				state = "豁免（已过期）"
+			}
+			line := fmt.Sprintf("%s: %s（审批人 %s，有效期至 %s）", state, w.Justification, w.Approver, w.Expires)
+			if len(w.Findings) > 0 {
+				line += " 范围: " + strings.Join(w.Findings, ", ")
+			}
+			fmt.Fprintln(out, line)
+		}
 		if item.Status == "fail" {
-			if hint := manualFixHint(item.ID); hint != "" {
+			if hint := manualFixHint(rc, item.ID); hint != "" {
 				fmt.Fprintf(out, "修复指引: %s\n", hint)
 			}
 		}
@@ -251,34 +615,16 @@ func runCheck(items []Item, jsonOut bool, outputFile string) {
 	if len(manualNames) > 0 {
 		fmt.Fprintf(out, "需人工确认项: %s\n", strings.Join(manualNames, "、"))
 	}
-	fmt.Fprintln(out, "============================================================")
-}
-
-func checkAndRepair(items []Item) error {
-	fmt.Println("自动修复已禁用，仅执行检查。")
-	runCheck(items, false, "")
-	return nil
-}
-
-func applyOne(items []Item, id string) error {
-	return errors.New("自动修复已禁用")
-}
-
-func applyAll(items []Item) error {
-	return errors.New("自动修复已禁用")
-}
-
-func findItem(items []Item, id string) (Item, bool) {
-	for _, item := range items {
-		if item.ID == id {
-			return item, true
-		}
+	if sum := payload.Summary; sum.Total > 0 {
+		fmt.Fprintf(out, "合规得分: %.1f（等级 %s，加权 %d/%d）\n", sum.Score, sum.Grade, sum.Earned, sum.Total)
+	} else {
+		fmt.Fprintln(out, "合规得分: 无可评分检查项")
 	}
-	return Item{}, false
+	fmt.Fprintln(out, "============================================================")
 }
 
-func readOSRelease() string {
-	info := detectOSInfo()
+func readOSRelease(rc *RunContext) string {
+	info := rc.OS
 	if info.Pretty != "" {
 		return info.Pretty
 	}
@@ -288,9 +634,9 @@ func readOSRelease() string {
 	return "Linux"
 }
 
-func pamFilesByDistro() ([]string, []string) {
+func pamFilesByDistro(rc *RunContext) ([]string, []string) {
 	// Debian-like vs RHEL-like PAM layout detection.
-	info := detectOSInfo()
+	info := rc.OS
 	id := strings.ToLower(info.ID)
 	name := strings.ToLower(info.Name)
 	idLike := strings.ToLower(info.IDLike)
@@ -305,23 +651,14 @@ func pamFilesByDistro() ([]string, []string) {
 	return []string{"/etc/pam.d/common-password", "/etc/pam.d/system-auth"}, []string{"/etc/pam.d/common-auth", "/etc/pam.d/system-auth"}
 }
 
-func firstExistingFile(paths []string) string {
-	for _, p := range paths {
-		if _, err := os.Stat(p); err == nil {
-			return p
-		}
-	}
-	return ""
-}
-
-func commandExists(name string) bool {
-	_, err := exec.LookPath(name)
-	return err == nil
-}
-
-func runCommand(name string, args ...string) (string, int) {
-	cmd := exec.Command(name, args...)
+func runCommand(ctx context.Context, name string, args ...string) (string, int) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	// Children such as apt's http methods may keep the pipe open after kill.
+	cmd.WaitDelay = 2 * time.Second
 	out, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return strings.TrimSpace(string(out)), -1
+	}
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			return strings.TrimSpace(string(out)), exitErr.ExitCode()
@@ -331,59 +668,91 @@ func runCommand(name string, args ...string) (string, int) {
 	return strings.TrimSpace(string(out)), 0
 }
 
-func checkFTPService() Result {
+func checkFTPService(rc *RunContext) Result {
 	services := []string{"vsftpd", "proftpd", "pure-ftpd", "ftpd"}
 	active := []string{}
-	if commandExists("systemctl") {
+	findings := []Finding{}
+	if rc.commandExists("systemctl") {
 		for _, svc := range services {
-			out, code := runCommand("systemctl", "is-active", svc)
-			if code == 0 && strings.TrimSpace(out) == "active" {
+			out, code := rc.runCommand("systemctl", "is-active", svc)
+			running := code == 0 && strings.TrimSpace(out) == "active"
+			if running {
 				active = append(active, svc)
 			}
+			findings = append(findings, commandFinding(svc, "FTP服务 "+svc, serviceState(running), "inactive", !running, "systemctl is-active "+svc))
 		}
-	} else if commandExists("pgrep") {
+	} else if rc.commandExists("pgrep") {
 		for _, svc := range services {
-			_, code := runCommand("pgrep", "-x", svc)
-			if code == 0 {
+			_, code := rc.runCommand("pgrep", "-x", svc)
+			running := code == 0
+			if running {
 				active = append(active, svc)
 			}
+			findings = append(findings, commandFinding(svc, "FTP服务 "+svc, serviceState(running), "inactive", !running, "pgrep -x "+svc))
 		}
 	}
 	if len(active) == 0 {
-		return Result{Status: "pass", Current: "未发现运行中的FTP服务"}
+		return Result{Status: "pass", Current: "未发现运行中的FTP服务", Findings: findings}
 	}
-	return Result{Status: "fail", Current: "运行中: " + strings.Join(active, ", ")}
+	return Result{Status: "fail", Current: "运行中: " + strings.Join(active, ", "), Findings: findings}
 }
 
-func checkNICInfo() Result {
-	if commandExists("ip") {
-		out, _ := runCommand("ip", "-o", "addr", "show")
+func serviceState(running bool) string {
+	if running {
+		return "active"
+	}
+	return "inactive"
+}
+
+func checkNICInfo(rc *RunContext) Result {
+	if rc.commandExists("ip") {
+		out, _ := rc.runCommand("ip", "-o", "addr", "show")
+		findings := []Finding{}
+		for _, line := range strings.Split(out, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 4 {
+				continue
+			}
+			findings = append(findings, Finding{
+				Key:      fields[1] + ":" + fields[2],
+				Label:    "网卡 " + fields[1],
+				Observed: fields[3],
+				Status:   "info",
+				Source:   &Evidence{Command: "ip -o addr show"},
+			})
+		}
 		if out == "" {
 			out = "未获取到网卡信息"
 		}
 		out = strings.ReplaceAll(out, "\n", "; ")
-		return Result{Status: "info", Current: out}
+		return Result{Status: "info", Current: out, Findings: findings}
 	}
 	return Result{Status: "manual", Current: "缺少ip命令，无法获取"}
 }
 
-func checkRiskyPorts() Result {
-	var out string
-	if commandExists("ss") {
-		out, _ = runCommand("ss", "-lntp")
-	} else if commandExists("netstat") {
-		out, _ = runCommand("netstat", "-lntp")
+func checkRiskyPorts(rc *RunContext) Result {
+	var out, listenCmd string
+	if rc.commandExists("ss") {
+		listenCmd = "ss -lntp"
+		out, _ = rc.runCommand("ss", "-lntp")
+	} else if rc.commandExists("netstat") {
+		listenCmd = "netstat -lntp"
+		out, _ = rc.runCommand("netstat", "-lntp")
 	}
 	ports := parseListeningPorts(out)
-	risky := riskyPorts()
+	risky := rc.Profile.RiskyPorts
 	opened := []int{}
+	findings := []Finding{}
 	for _, p := range risky {
 		if ports[p] {
 			opened = append(opened, p)
 		}
+		if listenCmd != "" {
+			findings = append(findings, commandFinding(fmt.Sprintf("listen:%d", p), fmt.Sprintf("端口%d监听", p), listenState(ports[p]), "not_listening", !ports[p], listenCmd))
+		}
 	}
 	// Combine runtime listening + firewall policy checks.
-	firewallStatus, missingBlocks, hint, hintKind := checkFirewallBlocks(risky)
+	firewallStatus, missingBlocks, hint, hintKind := checkFirewallBlocks(rc, risky)
 	status := "pass"
 	details := []string{}
 	if len(opened) > 0 {
@@ -399,14 +768,23 @@ func checkRiskyPorts() Result {
 	if firewallStatus == "absent" {
 		status = "fail"
 		details = append(details, "防火墙未安装")
+		findings = append(findings, commandFinding("firewall", "防火墙", "absent", "active", false, ""))
 	} else if firewallStatus == "inactive" {
 		status = "fail"
 		details = append(details, "防火墙未启用")
-	} else if len(missingBlocks) > 0 {
-		status = "fail"
-		details = append(details, "未封禁: "+strings.Join(missingBlocks, ", "))
+		findings = append(findings, commandFinding("firewall", "防火墙", "inactive", "active", false, ""))
 	} else {
-		details = append(details, "高危端口已配置封禁策略")
+		fwCmd := firewallEvidenceCommand(firewallStatus)
+		findings = append(findings, commandFinding("firewall", "防火墙", firewallStatus, "active", true, fwCmd))
+		for _, m := range missingBlocks {
+			findings = append(findings, commandFinding("block:"+m, m+"封禁", "not_blocked", "blocked", false, fwCmd))
+		}
+		if len(missingBlocks) > 0 {
+			status = "fail"
+			details = append(details, "未封禁: "+strings.Join(missingBlocks, ", "))
+		} else {
+			details = append(details, "高危端口已配置封禁策略")
+		}
 	}
 	if hint != "" {
 		switch hintKind {
@@ -418,11 +796,29 @@ func checkRiskyPorts() Result {
 			details = append(details, hint)
 		}
 	}
-	return Result{Status: status, Current: strings.Join(details, " | ")}
+	return Result{Status: status, Current: strings.Join(details, " | "), Findings: findings}
 }
 
-func riskyPorts() []int {
-	return []int{22, 23, 135, 137, 138, 139, 445, 455, 3389, 4899}
+func listenState(listening bool) string {
+	if listening {
+		return "listening"
+	}
+	return "not_listening"
+}
+
+func firewallEvidenceCommand(backend string) string {
+	switch backend {
+	case "ufw":
+		return "ufw status verbose"
+	case "firewalld":
+		return "firewall-cmd --list-rich-rules"
+	case "nftables":
+		return "nft list ruleset"
+	case "iptables":
+		return "iptables -S"
+	default:
+		return ""
+	}
 }
 
 func parseListeningPorts(output string) map[int]bool {
@@ -441,67 +837,66 @@ func parseListeningPorts(output string) map[int]bool {
 	return ports
 }
 
-func checkFirewallBlocks(ports []int) (string, []string, string, string) {
-	info := detectOSInfo()
-	kind := detectDistroKind(info)
+func checkFirewallBlocks(rc *RunContext, ports []int) (string, []string, string, string) {
+	kind := rc.Distro
 	if kind.IsUOS {
-		if commandExists("ufw") {
-			status, missing := checkUFWBlocks(ports)
+		if rc.commandExists("ufw") {
+			status, missing := checkUFWBlocks(rc, ports)
 			if status == "inactive" {
 				return "inactive", missing, "sudo ufw enable", "enable"
 			}
 			return status, missing, "", ""
 		}
-		if commandExists("iptables") {
-			status, missing := checkIptablesBlocksInputOnly(ports)
-			return status, missing, "建议安装UFW: " + pkgInstallCmd("ufw"), "recommend"
+		if rc.commandExists("iptables") {
+			status, missing := checkIptablesBlocksInputOnly(rc, ports)
+			return status, missing, "建议安装UFW: " + pkgInstallCmd(rc, "ufw"), "recommend"
 		}
-		return "absent", portsToProtoListInputOnly(ports), pkgInstallCmd("ufw"), "install"
+		return "absent", portsToProtoListInputOnly(ports), pkgInstallCmd(rc, "ufw"), "install"
 	}
 	if kind.IsKylin || kind.IsNeoKylin {
-		if commandExists("firewall-cmd") {
-			if !isServiceActive("firewalld") {
+		if rc.commandExists("firewall-cmd") {
+			if !isServiceActive(rc, "firewalld") {
 				return "inactive", portsToProtoListInputOnly(ports), "sudo systemctl enable --now firewalld", "enable"
 			}
-			status, missing := checkFirewalldBlocksInputOnly(ports)
+			status, missing := checkFirewalldBlocksInputOnly(rc, ports)
 			return status, missing, "", ""
 		}
-		if commandExists("iptables") {
-			status, missing := checkIptablesBlocksInputOnly(ports)
+		if rc.commandExists("iptables") {
+			status, missing := checkIptablesBlocksInputOnly(rc, ports)
 			return status, missing, "", ""
 		}
-		return "absent", portsToProtoListInputOnly(ports), pkgInstallCmd("firewalld"), "install"
+		return "absent", portsToProtoListInputOnly(ports), pkgInstallCmd(rc, "firewalld"), "install"
 	}
 	// Prefer native firewall tooling when available.
-	if commandExists("firewall-cmd") && isServiceActive("firewalld") {
-		status, missing := checkFirewalldBlocks(ports)
+	if rc.commandExists("firewall-cmd") && isServiceActive(rc, "firewalld") {
+		status, missing := checkFirewalldBlocks(rc, ports)
 		return status, missing, "", ""
 	}
-	if commandExists("nft") {
-		status, missing := checkNftBlocks(ports)
+	if rc.commandExists("nft") {
+		status, missing := checkNftBlocks(rc, ports)
 		return status, missing, "", ""
 	}
-	if commandExists("iptables") {
-		status, missing := checkIptablesBlocks(ports)
+	if rc.commandExists("iptables") {
+		status, missing := checkIptablesBlocks(rc, ports)
 		return status, missing, "", ""
 	}
-	return "absent", portsToProtoList(ports), firewallInstallHint(), "install"
+	return "absent", portsToProtoList(ports), firewallInstallHint(rc), "install"
 }
 
-func isServiceActive(service string) bool {
-	if !commandExists("systemctl") {
+func isServiceActive(rc *RunContext, service string) bool {
+	if !rc.commandExists("systemctl") {
 		return false
 	}
-	out, code := runCommand("systemctl", "is-active", service)
+	out, code := rc.runCommand("systemctl", "is-active", service)
 	return code == 0 && strings.TrimSpace(out) == "active"
 }
 
-func checkFirewalldBlocks(ports []int) (string, []string) {
+func checkFirewalldBlocks(rc *RunContext, ports []int) (string, []string) {
 	missing := []string{}
-	richRules, _ := runCommand("firewall-cmd", "--list-rich-rules")
+	richRules, _ := rc.runCommand("firewall-cmd", "--list-rich-rules")
 	for _, port := range ports {
 		for _, proto := range []string{"tcp", "udp"} {
-			if firewalldPortAllowed(port, proto) {
+			if firewalldPortAllowed(rc, port, proto) {
 				missing = append(missing, fmt.Sprintf("%d/%s(in)", port, proto))
 				missing = append(missing, fmt.Sprintf("%d/%s(out)", port, proto))
 				continue
@@ -517,12 +912,12 @@ func checkFirewalldBlocks(ports []int) (string, []string) {
 	return "firewalld", dedupeStrings(missing)
 }
 
-func checkFirewalldBlocksInputOnly(ports []int) (string, []string) {
+func checkFirewalldBlocksInputOnly(rc *RunContext, ports []int) (string, []string) {
 	missing := []string{}
-	richRules, _ := runCommand("firewall-cmd", "--list-rich-rules")
+	richRules, _ := rc.runCommand("firewall-cmd", "--list-rich-rules")
 	for _, port := range ports {
 		for _, proto := range []string{"tcp", "udp"} {
-			if firewalldPortAllowed(port, proto) {
+			if firewalldPortAllowed(rc, port, proto) {
 				missing = append(missing, fmt.Sprintf("%d/%s(in)", port, proto))
 				continue
 			}
@@ -534,8 +929,8 @@ func checkFirewalldBlocksInputOnly(ports []int) (string, []string) {
 	return "firewalld", dedupeStrings(missing)
 }
 
-func firewalldPortAllowed(port int, proto string) bool {
-	out, _ := runCommand("firewall-cmd", "--query-port", fmt.Sprintf("%d/%s", port, proto))
+func firewalldPortAllowed(rc *RunContext, port int, proto string) bool {
+	out, _ := rc.runCommand("firewall-cmd", "--query-port", fmt.Sprintf("%d/%s", port, proto))
 	return strings.TrimSpace(out) == "yes"
 }
 
@@ -558,8 +953,8 @@ func firewalldHasDenyRule(rules string, port int, proto string, direction string
 	return false
 }
 
-func checkUFWBlocks(ports []int) (string, []string) {
-	out, code := runCommand("ufw", "status", "verbose")
+func checkUFWBlocks(rc *RunContext, ports []int) (string, []string) {
+	out, code := rc.runCommand("ufw", "status", "verbose")
 	if code != 0 {
 		return "ufw", portsToProtoListInputOnly(ports)
 	}
@@ -606,9 +1001,9 @@ func checkUFWBlocks(ports []int) (string, []string) {
 	return "ufw", dedupeStrings(missing)
 }
 
-func checkIptablesBlocks(ports []int) (string, []string) {
-	inputRules, _ := runCommand("iptables", "-S", "INPUT")
-	outputRules, _ := runCommand("iptables", "-S", "OUTPUT")
+func checkIptablesBlocks(rc *RunContext, ports []int) (string, []string) {
+	inputRules, _ := rc.runCommand("iptables", "-S", "INPUT")
+	outputRules, _ := rc.runCommand("iptables", "-S", "OUTPUT")
 	missing := []string{}
 	for _, port := range ports {
 		for _, proto := range []string{"tcp", "udp"} {
@@ -623,8 +1018,8 @@ func checkIptablesBlocks(ports []int) (string, []string) {
 	return "iptables", dedupeStrings(missing)
 }
 
-func checkIptablesBlocksInputOnly(ports []int) (string, []string) {
-	inputRules, _ := runCommand("iptables", "-S", "INPUT")
+func checkIptablesBlocksInputOnly(rc *RunContext, ports []int) (string, []string) {
+	inputRules, _ := rc.runCommand("iptables", "-S", "INPUT")
 	missing := []string{}
 	for _, port := range ports {
 		for _, proto := range []string{"tcp", "udp"} {
@@ -650,8 +1045,8 @@ func iptablesHasDrop(rules string, port int, proto string) bool {
 	return false
 }
 
-func checkNftBlocks(ports []int) (string, []string) {
-	out, code := runCommand("nft", "list", "ruleset")
+func checkNftBlocks(rc *RunContext, ports []int) (string, []string) {
+	out, code := rc.runCommand("nft", "list", "ruleset")
 	if code != 0 {
 		return "nftables", portsToProtoList(ports)
 	}
@@ -722,46 +1117,43 @@ func dedupeStrings(items []string) []string {
 	return out
 }
 
-func checkIPv6Disabled() Result {
-	data, err := os.ReadFile("/proc/sys/net/ipv6/conf/all/disable_ipv6")
+func checkIPv6Disabled(rc *RunContext) Result {
+	path := "/proc/sys/net/ipv6/conf/all/disable_ipv6"
+	data, err := rc.Sys.ReadFile(path)
 	if err != nil {
 		return Result{Status: "manual", Current: "无法读取IPv6状态"}
 	}
 	value := strings.TrimSpace(string(data))
+	findings := []Finding{fileFinding("disable_ipv6", "net.ipv6.conf.all.disable_ipv6", value, "1", value == "1", path, 1)}
 	if value == "1" {
-		return Result{Status: "pass", Current: "disable_ipv6=1"}
+		return Result{Status: "pass", Current: "disable_ipv6=1", Findings: findings}
 	}
-	return Result{Status: "fail", Current: "disable_ipv6=" + value}
+	return Result{Status: "fail", Current: "disable_ipv6=" + value, Findings: findings}
 }
 
-func checkPatchUpdates() Result {
-	if commandExists("apt-get") {
-		out, _ := runCommand("apt-get", "-s", "upgrade")
+func checkPatchUpdates(rc *RunContext) Result {
+	if rc.commandExists("apt-get") {
+		out, _ := rc.runCommand("apt-get", "-s", "upgrade")
 		if count, ok := parseAptUpgradeCount(out); ok {
-			return parseUpgradeCount(count)
+			res := parseUpgradeCount(count)
+			res.Findings = []Finding{commandFinding("pending_updates", "待更新数量", fmt.Sprintf("%d", count), "0", count == 0, "apt-get -s upgrade")}
+			return res
 		}
 		return Result{Status: "manual", Current: "无法解析更新数量"}
 	}
-	if commandExists("dnf") {
-		_, code := runCommand("dnf", "check-update")
-		switch code {
-		case 0:
-			return Result{Status: "pass", Current: "dnf check-update"}
-		case 100:
-			return Result{Status: "fail", Current: "dnf有可更新包"}
-		default:
-			return Result{Status: "manual", Current: "dnf检查失败"}
+	for _, pm := range []string{"dnf", "yum"} {
+		if !rc.commandExists(pm) {
+			continue
 		}
-	}
-	if commandExists("yum") {
-		_, code := runCommand("yum", "check-update")
+		_, code := rc.runCommand(pm, "check-update")
+		command := pm + " check-update"
 		switch code {
 		case 0:
-			return Result{Status: "pass", Current: "yum check-update"}
+			return Result{Status: "pass", Current: command, Findings: []Finding{commandFinding("pending_updates", "待更新数量", "0", "0", true, command)}}
 		case 100:
-			return Result{Status: "fail", Current: "yum有可更新包"}
+			return Result{Status: "fail", Current: pm + "有可更新包", Findings: []Finding{commandFinding("pending_updates", "待更新数量", ">0", "0", false, command)}}
 		default:
-			return Result{Status: "manual", Current: "yum检查失败"}
+			return Result{Status: "manual", Current: pm + "检查失败"}
 		}
 	}
 	return Result{Status: "manual", Current: "未检测到包管理器"}
@@ -790,30 +1182,38 @@ func parseUpgradeCount(count int) Result {
 	return Result{Status: "fail", Current: fmt.Sprintf("待更新数量: %d", count)}
 }
 
-func checkPasswordPolicy() Result {
-	data, err := os.ReadFile("/etc/login.defs")
+func checkPasswordPolicy(rc *RunContext) Result {
+	loginDefs := "/etc/login.defs"
+	data, err := rc.Sys.ReadFile(loginDefs)
 	if err != nil {
 		return Result{Status: "manual", Current: "缺少/etc/login.defs"}
 	}
 	content := string(data)
 	maxKey, minKey, lenKey := loginDefsKeys(content)
-	maxDays := parseLoginDefsAny(content, []string{maxKey, "PASS_MAX_DAYS", "MAX_DAYS", "MAX"})
-	minDays := parseLoginDefsAny(content, []string{minKey, "PASS_MIN_DAYS", "MIN_DAYS", "MIN"})
-	minLen := parseLoginDefsAny(content, []string{lenKey, "PASS_MIN_LEN", "MIN_LEN", "LEN"})
+	maxDays, maxLine := parseLoginDefsAnyLine(content, []string{maxKey, "PASS_MAX_DAYS", "MAX_DAYS", "MAX"})
+	minDays, minLine := parseLoginDefsAnyLine(content, []string{minKey, "PASS_MIN_DAYS", "MIN_DAYS", "MIN"})
+	minLen, lenLine := parseLoginDefsAnyLine(content, []string{lenKey, "PASS_MIN_LEN", "MIN_LEN", "LEN"})
+	lenFile := loginDefs
 	pwqMinLen := ""
 	pwqMinClass := ""
 	if minLen == "" {
-		pwqMinLen, pwqMinClass = readPwqualityConfig()
+		pwqMinLen, pwqMinClass = readPwqualityConfig(rc)
+		if pwqMinLen != "" {
+			lenFile = "/etc/security/pwquality.conf"
+			lenLine = lineOf(rc.readFile(lenFile), `^\s*minlen\s*=`, true)
+		}
 	}
-	pamFiles, authFiles := pamFilesByDistro()
-	pamFile := firstExistingFile(pamFiles)
-	authFile := firstExistingFile(authFiles)
-	pamData, _ := os.ReadFile(pamFile)
+	pamFiles, authFiles := pamFilesByDistro(rc)
+	pamFile := rc.firstExistingFile(pamFiles)
+	authFile := rc.firstExistingFile(authFiles)
+	pamData, _ := rc.Sys.ReadFile(pamFile)
 	pamContent := string(pamData)
 	pwquality := strings.Contains(pamContent, "pam_pwquality.so") || strings.Contains(pamContent, "pam_cracklib.so")
 	if minLen == "" && pwqMinLen == "" {
 		if val := parsePamValue(pamContent, "minlen"); val != "" {
 			pwqMinLen = val
+			lenFile = pamFile
+			lenLine = lineOf(pamContent, `minlen=[0-9]+`, false)
 		}
 	}
 	if pwqMinClass == "" {
@@ -829,38 +1229,36 @@ func checkPasswordPolicy() Result {
 		pamMinClass = parsePamValue(pamContent, "minclass")
 	}
 	enforceRoot := strings.Contains(pamContent, "enforce_for_root")
-	rememberOK := strings.Contains(pamContent, "remember=5")
+	policy := rc.Profile.Password
+	remember := parsePamValue(pamContent, "remember")
+	rememberOK := policy.Remember == 0 || toInt(remember) >= policy.Remember
 	faillockOK := false
+	faillockLine := 0
 	if authFile != "" {
-		authContent := readFile(authFile)
+		authContent := rc.readFile(authFile)
 		faillockOK = strings.Contains(authContent, "pam_faillock.so") || strings.Contains(authContent, "pam_tally2.so")
+		faillockLine = lineOf(authContent, `pam_(faillock|tally2)\.so`, false)
+	}
+	complexityOK := pwquality && (pamMinClass == "" || toInt(pamMinClass) >= policy.MinClass) && enforceRoot
+	complexity := fmt.Sprintf("module=%s, minclass=%s, enforce_for_root=%t", boolToStr(pwquality), valueOrNA(pamMinClass), enforceRoot)
+
+	maxOK := maxDays != "" && toInt(maxDays) <= policy.MaxDays
+	minOK := minDays != "" && toInt(minDays) >= policy.MinDays
+	lenOK := minLen != "" && toInt(minLen) >= policy.MinLen
+	findings := []Finding{
+		fileFinding("pass_max_days", maxKey, valueOrNA(maxDays), fmt.Sprintf("<=%d", policy.MaxDays), maxOK, loginDefs, maxLine),
+		fileFinding("pass_min_days", minKey, valueOrNA(minDays), fmt.Sprintf(">=%d", policy.MinDays), minOK, loginDefs, minLine),
+		fileFinding("pass_min_len", lenKey, valueOrNA(minLen), fmt.Sprintf(">=%d", policy.MinLen), lenOK, lenFile, lenLine),
+		fileFinding("pam_complexity", "PAM复杂度", complexity, fmt.Sprintf("module=ok, minclass>=%d, enforce_for_root=true", policy.MinClass), complexityOK, pamFile, lineOf(pamContent, `pam_(pwquality|cracklib)\.so`, false)),
+		fileFinding("pam_remember", "PAM历史密码", valueOrNA(remember), fmt.Sprintf(">=%d", policy.Remember), rememberOK, pamFile, lineOf(pamContent, `remember=[0-9]+`, false)),
+		fileFinding("faillock", "登录失败锁定", boolToStr(faillockOK), "ok", faillockOK, authFile, faillockLine),
 	}
 
 	status := "pass"
 	issues := []string{}
-	if maxDays == "" || toInt(maxDays) > 90 {
-		status = "fail"
-		issues = append(issues, maxKey)
-	}
-	if minDays == "" || toInt(minDays) < 1 {
+	for _, f := range failedFindings(findings) {
 		status = "fail"
-		issues = append(issues, minKey)
-	}
-	if minLen == "" || toInt(minLen) < 10 {
-		status = "fail"
-		issues = append(issues, lenKey)
-	}
-	if !pwquality || (pamMinClass != "" && toInt(pamMinClass) < 4) || !enforceRoot {
-		status = "fail"
-		issues = append(issues, "PAM复杂度")
-	}
-	if !rememberOK {
-		status = "fail"
-		issues = append(issues, "PAM历史密码")
-	}
-	if !faillockOK {
-		status = "fail"
-		issues = append(issues, "登录失败锁定")
+		issues = append(issues, f.Label)
 	}
 	current := fmt.Sprintf("MAX=%s, MIN=%s, LEN=%s, PAM=%s", valueOrNA(maxDays), valueOrNA(minDays), valueOrNA(minLen), boolToStr(pwquality))
 	if pamMinClass != "" {
@@ -870,7 +1268,7 @@ func checkPasswordPolicy() Result {
 	if len(issues) > 0 {
 		current += " | 缺失: " + strings.Join(issues, ", ")
 	}
-	return Result{Status: status, Current: current}
+	return Result{Status: status, Current: current, Findings: findings}
 }
 
 func parseLoginDefs(data, key string) string {
@@ -883,12 +1281,18 @@ func parseLoginDefs(data, key string) string {
 }
 
 func parseLoginDefsAny(data string, keys []string) string {
+	value, _ := parseLoginDefsAnyLine(data, keys)
+	return value
+}
+
+// parseLoginDefsAnyLine also returns the 1-based line the value came from.
+func parseLoginDefsAnyLine(data string, keys []string) (string, int) {
 	for _, key := range keys {
 		if value := parseLoginDefs(data, key); value != "" {
-			return value
+			return value, lineOf(data, `^`+regexp.QuoteMeta(key)+`\s+\d+`, false)
 		}
 	}
-	return ""
+	return "", 0
 }
 
 func loginDefsKeys(content string) (string, string, string) {
@@ -927,8 +1331,8 @@ func firstMatchKey(content string, keys []string) string {
 	return ""
 }
 
-func readPwqualityConfig() (string, string) {
-	content, err := os.ReadFile("/etc/security/pwquality.conf")
+func readPwqualityConfig(rc *RunContext) (string, string) {
+	content, err := rc.Sys.ReadFile("/etc/security/pwquality.conf")
 	if err != nil {
 		return "", ""
 	}
@@ -965,111 +1369,107 @@ func parsePamValue(content, key string) string {
 	return ""
 }
 
-func ensurePwqualityConfig(minlen, minclass string) error {
+func ensurePwqualityConfig(rc *RunContext, minlen, minclass string) error {
 	path := "/etc/security/pwquality.conf"
-	if err := replaceOrAppendKV(path, "minlen", minlen, true); err != nil {
+	if err := replaceOrAppendKV(rc, path, "minlen", minlen, true); err != nil {
 		return err
 	}
-	if err := replaceOrAppendKV(path, "minclass", minclass, true); err != nil {
+	if err := replaceOrAppendKV(rc, path, "minclass", minclass, true); err != nil {
 		return err
 	}
 	return nil
 }
 
-func checkUSBAutoplay() Result {
-	config := "/etc/dconf/db/local.d/00-xc-baseline"
-	data, err := os.ReadFile(config)
+func checkUSBAutoplay(rc *RunContext) Result {
+	config := dconfConfigPath
+	data, err := rc.Sys.ReadFile(config)
 	if err != nil {
-		if commandExists("gsettings") {
-			if res, ok := checkUSBAutoplayGsettings(); ok {
-				return res
-			}
+		if res, ok := checkUSBAutoplayGsettings(rc); ok {
+			return res
 		}
 		return Result{Status: "manual", Current: "未发现dconf配置"}
 	}
 	content := string(data)
-	automount := strings.Contains(content, "automount=false")
-	automountOpen := strings.Contains(content, "automount-open=false")
+	automountRaw, automountLine := keyfileValue(content, dconfMediaHandling, "automount")
+	automountOpenRaw, automountOpenLine := keyfileValue(content, dconfMediaHandling, "automount-open")
+	automount := automountRaw == "false"
+	automountOpen := automountOpenRaw == "false"
+	findings := []Finding{
+		fileFinding("automount", "automount", valueOrNA(automountRaw), "false", automount, config, automountLine),
+		fileFinding("automount-open", "automount-open", valueOrNA(automountOpenRaw), "false", automountOpen, config, automountOpenLine),
+	}
 	if automount && automountOpen {
-		return Result{Status: "pass", Current: "automount=false, automount-open=false"}
+		return Result{Status: "pass", Current: "automount=false, automount-open=false", Findings: findings}
 	}
-	return Result{Status: "fail", Current: fmt.Sprintf("automount=%t, automount-open=%t", automount, automountOpen)}
+	return Result{Status: "fail", Current: fmt.Sprintf("automount=%t, automount-open=%t", automount, automountOpen), Findings: findings}
 }
 
-func checkUSBAutoplayGsettings() (Result, bool) {
+// dconfOfflineResult is the answer when an offline root has no dconf
+// file: gsettings would read the scanning host's session, not the image.
+var dconfOfflineResult = Result{Status: "not_applicable", Current: "离线扫描未发现dconf配置（gsettings仅反映本机会话）"}
+
+func checkUSBAutoplayGsettings(rc *RunContext) (Result, bool) {
+	if !rc.Sys.Live() {
+		return dconfOfflineResult, true
+	}
+	if !rc.commandExists("gsettings") {
+		return Result{}, false
+	}
 	candidates := []string{
 		"org.gnome.desktop.media-handling",
 		"org.ukui.desktop.media-handling",
 		"com.deepin.wrap.gnome.desktop.media-handling",
 	}
 	for _, schema := range candidates {
-		automount, okAuto := gsettingsGetBool(schema, "automount")
-		automountOpen, okOpen := gsettingsGetBool(schema, "automount-open")
+		automount, okAuto := gsettingsGetBool(rc, schema, "automount")
+		automountOpen, okOpen := gsettingsGetBool(rc, schema, "automount-open")
 		if !okAuto || !okOpen {
 			continue
 		}
+		findings := []Finding{
+			commandFinding("automount", "automount", fmt.Sprintf("%t", automount), "false", !automount, "gsettings get "+schema+" automount"),
+			commandFinding("automount-open", "automount-open", fmt.Sprintf("%t", automountOpen), "false", !automountOpen, "gsettings get "+schema+" automount-open"),
+		}
 		if !automount && !automountOpen {
-			return Result{Status: "pass", Current: fmt.Sprintf("%s: automount=false, automount-open=false", schema)}, true
+			return Result{Status: "pass", Current: fmt.Sprintf("%s: automount=false, automount-open=false", schema), Findings: findings}, true
 		}
-		return Result{Status: "fail", Current: fmt.Sprintf("%s: automount=%t, automount-open=%t", schema, automount, automountOpen)}, true
+		return Result{Status: "fail", Current: fmt.Sprintf("%s: automount=%t, automount-open=%t", schema, automount, automountOpen), Findings: findings}, true
 	}
 	return Result{}, false
 }
 
-func applyUSBAutoplay() error {
-	config := "/etc/dconf/db/local.d/00-xc-baseline"
-	lockFile := "/etc/dconf/db/local.d/locks/00-xc-baseline"
-	if err := os.MkdirAll(filepath.Dir(lockFile), 0755); err != nil {
-		return err
-	}
-	content := readFile(config)
-	content = ensureSection(content, "org/gnome/desktop/media-handling")
-	content = upsertKey(content, "automount", "false")
-	content = upsertKey(content, "automount-open", "false")
-	if err := os.WriteFile(config, []byte(content), 0644); err != nil {
-		return err
-	}
-	lockContent := readFile(lockFile)
-	lockContent = ensureLock(lockContent, "/org/gnome/desktop/media-handling/automount")
-	lockContent = ensureLock(lockContent, "/org/gnome/desktop/media-handling/automount-open")
-	if err := os.WriteFile(lockFile, []byte(lockContent), 0644); err != nil {
-		return err
-	}
-	if commandExists("dconf") {
-		_, _ = runCommand("dconf", "update")
-	}
-	return nil
+func applyUSBAutoplay(rc *RunContext) error {
+	return applyDconfSettings(rc, usbAutoplaySettings())
 }
 
-func checkLockScreen() Result {
-	config := "/etc/dconf/db/local.d/00-xc-baseline"
-	data, err := os.ReadFile(config)
+func checkLockScreen(rc *RunContext) Result {
+	config := dconfConfigPath
+	data, err := rc.Sys.ReadFile(config)
 	if err != nil {
-		return checkLockScreenGsettings()
+		return checkLockScreenGsettings(rc)
 	}
 	content := string(data)
-	lockEnabled := strings.Contains(content, "lock-enabled=true")
-	idleDelayRaw := findConfigValue(content, "idle-delay")
-	lockDelayRaw := findConfigValue(content, "lock-delay")
+	limits := rc.Profile.LockScreen
+	lockEnabledRaw, lockEnabledLine := keyfileValue(content, dconfScreensaver, "lock-enabled")
+	idleDelayRaw, idleDelayLine := keyfileValue(content, dconfSession, "idle-delay")
+	lockDelayRaw, lockDelayLine := keyfileValue(content, dconfScreensaver, "lock-delay")
+	lockEnabled := lockEnabledRaw == "true"
 	idleDelayNum, idleOK := parseTrailingInt(idleDelayRaw)
 	lockDelayNum, lockOK := parseTrailingInt(lockDelayRaw)
-	if lockEnabled && idleOK && idleDelayNum <= 900 && lockOK && lockDelayNum == 0 {
-		return Result{Status: "pass", Current: fmt.Sprintf("lock-enabled=true, idle-delay=%s, lock-delay=%s", valueOrNA(idleDelayRaw), valueOrNA(lockDelayRaw))}
+	findings := []Finding{
+		fileFinding("lock-enabled", "lock-enabled", valueOrNA(lockEnabledRaw), "true", lockEnabled, config, lockEnabledLine),
+		fileFinding("idle-delay", "idle-delay", valueOrNA(idleDelayRaw), fmt.Sprintf("<=%d", limits.IdleDelay), idleOK && idleDelayNum <= limits.IdleDelay, config, idleDelayLine),
+		fileFinding("lock-delay", "lock-delay", valueOrNA(lockDelayRaw), fmt.Sprintf("<=%d", limits.LockDelay), lockOK && lockDelayNum <= limits.LockDelay, config, lockDelayLine),
 	}
-	fallback := checkLockScreenGsettings()
-	if fallback.Status != "manual" {
-		return fallback
+	if len(failedFindings(findings)) == 0 {
+		return Result{Status: "pass", Current: fmt.Sprintf("lock-enabled=true, idle-delay=%s, lock-delay=%s", valueOrNA(idleDelayRaw), valueOrNA(lockDelayRaw)), Findings: findings}
 	}
-	return Result{Status: "fail", Current: fmt.Sprintf("lock-enabled=%t, idle-delay=%s, lock-delay=%s", lockEnabled, valueOrNA(idleDelayRaw), valueOrNA(lockDelayRaw))}
-}
-
-func findConfigValue(content, key string) string {
-	re := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(key) + `\s*=\s*(.+)$`)
-	matches := re.FindAllStringSubmatch(content, -1)
-	if len(matches) > 0 {
-		return strings.TrimSpace(matches[len(matches)-1][1])
+	if rc.Sys.Live() {
+		if fallback := checkLockScreenGsettings(rc); fallback.Status != "manual" {
+			return fallback
+		}
 	}
-	return ""
+	return Result{Status: "fail", Current: fmt.Sprintf("lock-enabled=%t, idle-delay=%s, lock-delay=%s", lockEnabled, valueOrNA(idleDelayRaw), valueOrNA(lockDelayRaw)), Findings: findings}
 }
 
 func parseTrailingInt(s string) (int, bool) {
@@ -1081,8 +1481,11 @@ func parseTrailingInt(s string) (int, bool) {
 	return 0, false
 }
 
-func checkLockScreenGsettings() Result {
-	if !commandExists("gsettings") {
+func checkLockScreenGsettings(rc *RunContext) Result {
+	if !rc.Sys.Live() {
+		return dconfOfflineResult
+	}
+	if !rc.commandExists("gsettings") {
 		return Result{Status: "manual", Current: "未发现dconf配置"}
 	}
 	type schemaSet struct {
@@ -1095,48 +1498,104 @@ func checkLockScreenGsettings() Result {
 		{SessionSchema: "com.deepin.wrap.gnome.desktop.session", ScreensSchema: "com.deepin.wrap.gnome.desktop.screensaver"},
 	}
 	for _, cand := range candidates {
-		idleRaw, okIdle := gsettingsGet(cand.SessionSchema, "idle-delay")
-		lockEnabledRaw, okLock := gsettingsGet(cand.ScreensSchema, "lock-enabled")
-		lockDelayRaw, okDelay := gsettingsGet(cand.ScreensSchema, "lock-delay")
+		idleRaw, okIdle := gsettingsGet(rc, cand.SessionSchema, "idle-delay")
+		lockEnabledRaw, okLock := gsettingsGet(rc, cand.ScreensSchema, "lock-enabled")
+		lockDelayRaw, okDelay := gsettingsGet(rc, cand.ScreensSchema, "lock-delay")
 		if !okIdle || !okLock || !okDelay {
 			continue
 		}
 		idleNum, idleOK := parseTrailingInt(idleRaw)
 		lockDelayNum, lockOK := parseTrailingInt(lockDelayRaw)
 		lockEnabled := strings.TrimSpace(lockEnabledRaw) == "true"
-		if lockEnabled && idleOK && idleNum <= 900 && lockOK && lockDelayNum == 0 {
-			return Result{Status: "pass", Current: fmt.Sprintf("lock-enabled=true, idle-delay=%s, lock-delay=%s", valueOrNA(idleRaw), valueOrNA(lockDelayRaw))}
+		limits := rc.Profile.LockScreen
+		findings := []Finding{
+			commandFinding("lock-enabled", "lock-enabled", valueOrNA(lockEnabledRaw), "true", lockEnabled, "gsettings get "+cand.ScreensSchema+" lock-enabled"),
+			commandFinding("idle-delay", "idle-delay", valueOrNA(idleRaw), fmt.Sprintf("<=%d", limits.IdleDelay), idleOK && idleNum <= limits.IdleDelay, "gsettings get "+cand.SessionSchema+" idle-delay"),
+			commandFinding("lock-delay", "lock-delay", valueOrNA(lockDelayRaw), fmt.Sprintf("<=%d", limits.LockDelay), lockOK && lockDelayNum <= limits.LockDelay, "gsettings get "+cand.ScreensSchema+" lock-delay"),
+		}
+		if len(failedFindings(findings)) == 0 {
+			return Result{Status: "pass", Current: fmt.Sprintf("lock-enabled=true, idle-delay=%s, lock-delay=%s", valueOrNA(idleRaw), valueOrNA(lockDelayRaw)), Findings: findings}
 		}
-		return Result{Status: "fail", Current: fmt.Sprintf("lock-enabled=%t, idle-delay=%s, lock-delay=%s", lockEnabled, valueOrNA(idleRaw), valueOrNA(lockDelayRaw))}
+		return Result{Status: "fail", Current: fmt.Sprintf("lock-enabled=%t, idle-delay=%s, lock-delay=%s", lockEnabled, valueOrNA(idleRaw), valueOrNA(lockDelayRaw)), Findings: findings}
 	}
 	return Result{Status: "manual", Current: "未发现dconf配置"}
 }
 
-func checkAuditRules() Result {
-	installed := commandExists("auditctl") || fileExists("/sbin/auditd") || fileExists("/usr/sbin/auditd")
+func checkAuditRules(rc *RunContext) Result {
+	installed := rc.commandExists("auditctl") || rc.fileExists("/sbin/auditd") || rc.fileExists("/usr/sbin/auditd")
 	if !installed {
-		return Result{Status: "fail", Current: "未安装auditd"}
+		return Result{Status: "fail", Current: "未安装auditd", Findings: []Finding{
+			fileFinding("auditd_installed", "auditd安装", "missing", "ok", false, "/usr/sbin/auditd", 0),
+		}}
+	}
+	findings := []Finding{fileFinding("auditd_installed", "auditd安装", "ok", "ok", true, "", 0)}
+	activeLabel, inactiveLabel := "auditd运行中", "auditd未运行"
+	var active bool
+	if rc.Sys.Live() {
+		active = isServiceActive(rc, "auditd")
+		command := "systemctl is-active auditd"
+		if !active && rc.commandExists("pgrep") {
+			_, code := rc.runCommand("pgrep", "-x", "auditd")
+			active = code == 0
+			command = "pgrep -x auditd"
+		}
+		findings = append(findings, commandFinding("auditd_active", "auditd运行", serviceState(active), "active", active, command))
+	} else {
+		// Offline images have no running services; check boot-time enablement instead.
+		activeLabel, inactiveLabel = "auditd已设置开机启动", "auditd未设置开机启动"
+		active = unitEnabledOffline(rc, "auditd.service")
+		findings = append(findings, fileFinding("auditd_active", "auditd开机启动", boolToStr(active), "ok", active, "/etc/systemd/system/multi-user.target.wants/auditd.service", 0))
 	}
-	active := isServiceActive("auditd")
-	if !active && commandExists("pgrep") {
-		_, code := runCommand("pgrep", "-x", "auditd")
-		active = code == 0
+	ruleCount, ruleSources := auditRuleSummary(rc)
+	countFinding := Finding{Key: "rule_count", Label: "审计规则数", Observed: fmt.Sprintf("%d", ruleCount), Expected: ">0", Status: passFail(ruleCount > 0)}
+	if len(ruleSources) > 0 {
+		countFinding.Source = &Evidence{File: ruleSources[0]}
+	}
+	findings = append(findings, countFinding)
+	missing := missingAuditRules(rc, rc.Profile.AuditRules)
+	missingSet := map[string]bool{}
+	for _, rule := range missing {
+		missingSet[rule] = true
+	}
+	for _, rule := range rc.Profile.AuditRules {
+		observed := "present"
+		if missingSet[rule] {
+			observed = "missing"
+		}
+		findings = append(findings, Finding{Key: "rule:" + rule, Label: "审计规则", Observed: observed, Expected: "present", Status: passFail(!missingSet[rule])})
 	}
-	ruleCount, ruleSources := auditRuleSummary()
 	if !active {
-		return Result{Status: "fail", Current: "auditd未运行 | 规则数: " + fmt.Sprintf("%d", ruleCount) + " | 规则文件: " + strings.Join(ruleSources, ", ")}
+		return Result{Status: "fail", Current: inactiveLabel + " | 规则数: " + fmt.Sprintf("%d", ruleCount) + " | 规则文件: " + strings.Join(ruleSources, ", "), Findings: findings}
 	}
 	if ruleCount == 0 {
-		return Result{Status: "fail", Current: "auditd运行但未发现规则"}
+		return Result{Status: "fail", Current: activeLabel + "但未发现规则", Findings: findings}
 	}
-	return Result{Status: "pass", Current: fmt.Sprintf("auditd运行中 | 规则数: %d | 规则文件: %s", ruleCount, strings.Join(ruleSources, ", "))}
+	if len(missing) > 0 {
+		return Result{Status: "fail", Current: fmt.Sprintf("%s | 规则数: %d | 缺少规则: %s", activeLabel, ruleCount, strings.Join(missing, "; ")), Findings: findings}
+	}
+	return Result{Status: "pass", Current: fmt.Sprintf("%s | 规则数: %d | 规则文件: %s", activeLabel, ruleCount, strings.Join(ruleSources, ", ")), Findings: findings}
+}
+
+// unitEnabledOffline looks for the enablement symlink itself; its target is
+// usually an absolute path that only resolves inside the running image.
+func unitEnabledOffline(rc *RunContext, unit string) bool {
+	entries, err := rc.Sys.ReadDir("/etc/systemd/system/multi-user.target.wants")
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if entry.Name() == unit {
+			return true
+		}
+	}
+	return false
 }
 
-func auditRuleSummary() (int, []string) {
+func auditRuleSummary(rc *RunContext) (int, []string) {
 	ruleCount := 0
 	sources := []string{}
 	paths := []string{"/etc/audit/audit.rules"}
-	if entries, err := os.ReadDir("/etc/audit/rules.d"); err == nil {
+	if entries, err := rc.Sys.ReadDir("/etc/audit/rules.d"); err == nil {
 		for _, entry := range entries {
 			if entry.IsDir() {
 				continue
@@ -1147,7 +1606,7 @@ func auditRuleSummary() (int, []string) {
 		}
 	}
 	for _, path := range paths {
-		count := countAuditRules(path)
+		count := countAuditRules(rc, path)
 		if count > 0 {
 			ruleCount += count
 			sources = append(sources, path)
@@ -1156,8 +1615,37 @@ func auditRuleSummary() (int, []string) {
 	return ruleCount, sources
 }
 
-func countAuditRules(path string) int {
-	data, err := os.ReadFile(path)
+// missingAuditRules compares required rules against the rule files and,
+// when permitted, the rules loaded in the kernel. Whitespace is normalized.
+func missingAuditRules(rc *RunContext, required []string) []string {
+	if len(required) == 0 {
+		return nil
+	}
+	present := map[string]bool{}
+	_, sources := auditRuleSummary(rc)
+	for _, path := range sources {
+		for _, line := range rc.readLines(path) {
+			present[strings.Join(strings.Fields(line), " ")] = true
+		}
+	}
+	if rc.commandExists("auditctl") {
+		if out, code := rc.runCommand("auditctl", "-l"); code == 0 {
+			for _, line := range strings.Split(out, "\n") {
+				present[strings.Join(strings.Fields(line), " ")] = true
+			}
+		}
+	}
+	missing := []string{}
+	for _, rule := range required {
+		if !present[strings.Join(strings.Fields(rule), " ")] {
+			missing = append(missing, rule)
+		}
+	}
+	return missing
+}
+
+func countAuditRules(rc *RunContext, path string) int {
+	data, err := rc.Sys.ReadFile(path)
 	if err != nil {
 		return 0
 	}
@@ -1172,21 +1660,16 @@ func countAuditRules(path string) int {
 	return count
 }
 
-func fileExists(path string) bool {
-	_, err := os.Stat(path)
-	return err == nil
-}
-
-func gsettingsGet(schema, key string) (string, bool) {
-	out, code := runCommand("gsettings", "get", schema, key)
+func gsettingsGet(rc *RunContext, schema, key string) (string, bool) {
+	out, code := rc.runCommand("gsettings", "get", schema, key)
 	if code != 0 {
 		return "", false
 	}
 	return strings.TrimSpace(out), true
 }
 
-func gsettingsGetBool(schema, key string) (bool, bool) {
-	out, ok := gsettingsGet(schema, key)
+func gsettingsGetBool(rc *RunContext, schema, key string) (bool, bool) {
+	out, ok := gsettingsGet(rc, schema, key)
 	if !ok {
 		return false, false
 	}
@@ -1200,256 +1683,374 @@ func gsettingsGetBool(schema, key string) (bool, bool) {
 	return false, false
 }
 
-func applyFTPService() error {
+func applyFTPService(rc *RunContext) error {
 	services := []string{"vsftpd", "proftpd", "pure-ftpd", "ftpd"}
 	for _, svc := range services {
-		if commandExists("systemctl") {
-			_, _ = runCommand("systemctl", "stop", svc)
-			_, _ = runCommand("systemctl", "disable", svc)
-		} else if commandExists("service") {
-			_, _ = runCommand("service", svc, "stop")
+		if rc.commandExists("systemctl") {
+			_, _ = rc.mutate("systemctl", "stop", svc)
+			_, _ = rc.mutate("systemctl", "disable", svc)
+		} else if rc.commandExists("service") {
+			_, _ = rc.mutate("service", svc, "stop")
 		}
 	}
 	return nil
 }
 
-func applyRiskyPorts() error {
+func applyRiskyPorts(rc *RunContext) error {
 	// Auto-fix via the first available firewall backend.
-	ports := riskyPorts()
-	if commandExists("firewall-cmd") {
-		return applyFirewalldBlocks(ports)
+	ports := rc.Profile.RiskyPorts
+	if rc.commandExists("firewall-cmd") {
+		return applyFirewalldBlocks(rc, ports)
 	}
-	if commandExists("nft") {
-		if err := applyNftBlocks(ports); err == nil {
+	if rc.commandExists("nft") {
+		if err := applyNftBlocks(rc, ports); err == nil {
 			return nil
 		}
 	}
-	if commandExists("iptables") {
-		return applyIptablesBlocks(ports)
+	if rc.commandExists("iptables") {
+		return applyIptablesBlocks(rc, ports)
 	}
 	return errors.New("未检测到可用防火墙组件")
 }
 
-func applyFirewalldBlocks(ports []int) error {
-	if commandExists("systemctl") {
-		_, _ = runCommand("systemctl", "enable", "--now", "firewalld")
+func applyFirewalldBlocks(rc *RunContext, ports []int) error {
+	if rc.commandExists("systemctl") {
+		_, _ = rc.mutate("systemctl", "enable", "--now", "firewalld")
 	}
-	if !isServiceActive("firewalld") {
+	if !rc.dryRun() && !isServiceActive(rc, "firewalld") {
 		return errors.New("firewalld 未运行")
 	}
+	for _, rule := range firewalldRichRules(ports) {
+		_, _ = rc.mutate("firewall-cmd", "--permanent", "--add-rich-rule", rule)
+	}
+	_, _ = rc.mutate("firewall-cmd", "--reload")
+	return nil
+}
+
+func firewalldRichRules(ports []int) []string {
+	rules := []string{}
 	for _, port := range ports {
 		for _, proto := range []string{"tcp", "udp"} {
 			for _, family := range []string{"ipv4", "ipv6"} {
-				_, _ = runCommand("firewall-cmd", "--permanent", "--add-rich-rule",
-					fmt.Sprintf("rule family=\"%s\" port port=\"%d\" protocol=\"%s\" reject", family, port, proto))
-				_, _ = runCommand("firewall-cmd", "--permanent", "--add-rich-rule",
+				rules = append(rules,
+					fmt.Sprintf("rule family=\"%s\" port port=\"%d\" protocol=\"%s\" reject", family, port, proto),
 					fmt.Sprintf("rule family=\"%s\" direction=\"out\" port port=\"%d\" protocol=\"%s\" reject", family, port, proto))
 			}
 		}
 	}
-	_, _ = runCommand("firewall-cmd", "--reload")
-	return nil
+	return rules
 }
 
-func applyIptablesBlocks(ports []int) error {
+func applyIptablesBlocks(rc *RunContext, ports []int) error {
 	for _, port := range ports {
 		for _, proto := range []string{"tcp", "udp"} {
-			ensureIptablesRule("iptables", "INPUT", proto, port)
-			ensureIptablesRule("iptables", "OUTPUT", proto, port)
-			if commandExists("ip6tables") {
-				ensureIptablesRule("ip6tables", "INPUT", proto, port)
-				ensureIptablesRule("ip6tables", "OUTPUT", proto, port)
+			ensureIptablesRule(rc, "iptables", "INPUT", proto, port)
+			ensureIptablesRule(rc, "iptables", "OUTPUT", proto, port)
+			if rc.commandExists("ip6tables") {
+				ensureIptablesRule(rc, "ip6tables", "INPUT", proto, port)
+				ensureIptablesRule(rc, "ip6tables", "OUTPUT", proto, port)
 			}
 		}
 	}
 	return nil
 }
 
-func ensureIptablesRule(bin, chain, proto string, port int) {
+func ensureIptablesRule(rc *RunContext, bin, chain, proto string, port int) {
 	args := []string{"-C", chain, "-p", proto, "--dport", fmt.Sprintf("%d", port), "-j", "DROP"}
-	_, code := runCommand(bin, args...)
+	_, code := rc.runCommand(bin, args...)
 	if code == 0 {
 		return
 	}
 	addArgs := []string{"-A", chain, "-p", proto, "--dport", fmt.Sprintf("%d", port), "-j", "DROP"}
-	_, _ = runCommand(bin, addArgs...)
+	_, _ = rc.mutate(bin, addArgs...)
 }
 
-func applyNftBlocks(ports []int) error {
-	_, _ = runCommand("nft", "add", "table", "inet", "filter")
-	_, _ = runCommand("nft", "add", "chain", "inet", "filter", "input",
+func applyNftBlocks(rc *RunContext, ports []int) error {
+	_, _ = rc.mutate("nft", "add", "table", "inet", "filter")
+	_, _ = rc.mutate("nft", "add", "chain", "inet", "filter", "input",
 		"{", "type", "filter", "hook", "input", "priority", "0", ";", "}")
-	_, _ = runCommand("nft", "add", "chain", "inet", "filter", "output",
+	_, _ = rc.mutate("nft", "add", "chain", "inet", "filter", "output",
 		"{", "type", "filter", "hook", "output", "priority", "0", ";", "}")
 	for _, port := range ports {
 		for _, proto := range []string{"tcp", "udp"} {
-			_, _ = runCommand("nft", "add", "rule", "inet", "filter", "input", proto, "dport", fmt.Sprintf("%d", port), "drop")
-			_, _ = runCommand("nft", "add", "rule", "inet", "filter", "output", proto, "dport", fmt.Sprintf("%d", port), "drop")
+			_, _ = rc.mutate("nft", "add", "rule", "inet", "filter", "input", proto, "dport", fmt.Sprintf("%d", port), "drop")
+			_, _ = rc.mutate("nft", "add", "rule", "inet", "filter", "output", proto, "dport", fmt.Sprintf("%d", port), "drop")
 		}
 	}
 	return nil
 }
 
-func applyIPv6Disabled() error {
-	path := "/etc/sysctl.d/99-xc-baseline.conf"
-	lines := readLines(path)
-	lines = upsertKVAllowComment(lines, "net.ipv6.conf.all.disable_ipv6", "1", false)
-	lines = upsertKVAllowComment(lines, "net.ipv6.conf.default.disable_ipv6", "1", false)
-	lines = upsertKVAllowComment(lines, "net.ipv6.conf.lo.disable_ipv6", "1", false)
-	if err := writeLines(path, lines); err != nil {
+func applyIPv6Disabled(rc *RunContext) error {
+	path := ipv6SysctlPath
+	lines := rc.readLines(path)
+	for _, key := range ipv6SysctlKeys() {
+		lines = upsertKVAllowComment(lines, key, "1", false)
+	}
+	if err := writeLines(rc, path, lines); err != nil {
 		return err
 	}
-	if commandExists("sysctl") {
-		_, _ = runCommand("sysctl", "-p", path)
-		_, _ = runCommand("sysctl", "-w", "net.ipv6.conf.all.disable_ipv6=1")
-		_, _ = runCommand("sysctl", "-w", "net.ipv6.conf.default.disable_ipv6=1")
-		_, _ = runCommand("sysctl", "-w", "net.ipv6.conf.lo.disable_ipv6=1")
-		_, _ = runCommand("sysctl", "--system")
-	}
-	writeProcValue("/proc/sys/net/ipv6/conf/all/disable_ipv6", "1")
-	writeProcValue("/proc/sys/net/ipv6/conf/default/disable_ipv6", "1")
-	writeProcValue("/proc/sys/net/ipv6/conf/lo/disable_ipv6", "1")
-	if readProcValue("/proc/sys/net/ipv6/conf/all/disable_ipv6") != "1" {
+	if rc.commandExists("sysctl") {
+		_, _ = rc.mutate("sysctl", "-p", path)
+		for _, key := range ipv6SysctlKeys() {
+			_, _ = rc.mutate("sysctl", "-w", key+"=1")
+		}
+		_, _ = rc.mutate("sysctl", "--system")
+	}
+	for _, key := range ipv6SysctlKeys() {
+		if err := rc.writeProcValue("/proc/sys/"+strings.ReplaceAll(key, ".", "/"), "1"); err != nil {
+			return err
+		}
+	}
+	if readProcValue(rc, "/proc/sys/net/ipv6/conf/all/disable_ipv6") != "1" {
 		return errors.New("IPv6禁用未生效，请确认系统未被策略覆盖")
 	}
 	return nil
 }
 
-func applyPasswordPolicy() error {
+func applyPasswordPolicy(rc *RunContext) error {
 	// Password policy differs by distro family; detect and write the right PAM files.
+	policy := rc.Profile.Password
+	maxDays := fmt.Sprintf("%d", policy.MaxDays)
+	minDays := fmt.Sprintf("%d", policy.MinDays)
+	minLen := fmt.Sprintf("%d", policy.MinLen)
+	minClass := fmt.Sprintf("%d", policy.MinClass)
 	loginDefs := "/etc/login.defs"
-	content := readFile(loginDefs)
+	content := rc.readFile(loginDefs)
 	maxKey, minKey, lenKey := loginDefsKeys(content)
-	if err := replaceOrAppendKV(loginDefs, maxKey, "90", false); err != nil {
-		return err
-	}
-	if err := replaceOrAppendKV(loginDefs, minKey, "1", false); err != nil {
-		return err
-	}
-	if err := replaceOrAppendKV(loginDefs, lenKey, "10", false); err != nil {
-		return err
+	for _, kv := range loginDefsSettings(policy, maxKey, minKey, lenKey) {
+		if err := replaceOrAppendKV(rc, loginDefs, kv.Key, kv.Value, false); err != nil && kv.Key != "PASS_WARN_AGE" {
+			return err
+		}
 	}
-	_ = replaceOrAppendKV(loginDefs, "PASS_WARN_AGE", "7", false)
 
 	// Some Kylin builds honor pwquality.conf over PASS_MIN_LEN.
-	if err := ensurePwqualityConfig("10", "4"); err != nil {
+	if err := ensurePwqualityConfig(rc, minLen, minClass); err != nil {
 		return err
 	}
 
-	pamFiles, authFiles := pamFilesByDistro()
-	pamFile := firstExistingFile(pamFiles)
-	authFile := firstExistingFile(authFiles)
+	pamFiles, authFiles := pamFilesByDistro(rc)
+	pamFile := rc.firstExistingFile(pamFiles)
+	authFile := rc.firstExistingFile(authFiles)
 	if pamFile == "" {
 		return errors.New("未找到PAM密码配置文件")
 	}
-	target := "password requisite pam_pwquality.so retry=3 enforce_for_root minlen=10 minclass=4"
-	pamLines := readLines(pamFile)
-	found := false
-	for i, line := range pamLines {
-		if strings.Contains(line, "pam_pwquality.so") || strings.Contains(line, "pam_cracklib.so") {
-			pamLines[i] = target
-			found = true
-		}
+	// Every PAM edit is computed before anything is written, so a stack
+	// without the expected anchors is left untouched.
+	pamLines, err := ensurePasswordPamLines(rc.readLines(pamFile), policy)
+	if err != nil {
+		return fmt.Errorf("%s: %w", pamFile, err)
 	}
-	if !found {
-		pamLines = append(pamLines, target)
+	var authLines []string
+	if authFile != "" {
+		authLines = rc.readLines(authFile)
+		if authFile == pamFile {
+			authLines = pamLines
+		}
+		var lines []pamLine
+		if pamModuleExists(rc, "pam_faillock.so") {
+			lines = faillockPamLines(policy)
+		} else if pamModuleExists(rc, "pam_tally2.so") {
+			lines = tally2PamLines(policy)
+		}
+		for _, line := range lines {
+			if authLines, err = ensurePamLine(authLines, line); err != nil {
+				return fmt.Errorf("%s: %w", authFile, err)
+			}
+		}
+		if authFile == pamFile {
+			pamLines = authLines
+		}
 	}
-	if err := writeLines(pamFile, pamLines); err != nil {
+	if err := writeLines(rc, pamFile, pamLines); err != nil {
 		return err
 	}
-	pamLines = readLines(pamFile)
-	unixLine := "password sufficient pam_unix.so sha512 shadow remember=5 use_authtok"
-	unixFound := false
-	for i, line := range pamLines {
-		if strings.Contains(line, "pam_unix.so") && strings.Contains(line, "password") {
-			pamLines[i] = unixLine
-			unixFound = true
+	if authFile != "" && authFile != pamFile {
+		if err := writeLines(rc, authFile, authLines); err != nil {
+			return err
 		}
 	}
-	if !unixFound {
-		pamLines = append(pamLines, unixLine)
-	}
-	if err := writeLines(pamFile, pamLines); err != nil {
-		return err
-	}
-
 	if authFile != "" {
-		authLines := readLines(authFile)
-		if pamModuleExists("pam_faillock.so") {
-			authLines = ensurePamLine(authLines, "pam_faillock.so preauth", "auth required pam_faillock.so preauth silent deny=5 unlock_time=600")
-			authLines = ensurePamLine(authLines, "pam_faillock.so authfail", "auth [default=die] pam_faillock.so authfail deny=5 unlock_time=600")
-			authLines = ensurePamLine(authLines, "account required pam_faillock.so", "account required pam_faillock.so")
-		} else if pamModuleExists("pam_tally2.so") {
-			authLines = ensurePamLine(authLines, "pam_tally2.so", "auth required pam_tally2.so deny=5 unlock_time=600")
-			authLines = ensurePamLine(authLines, "pam_tally2.so", "account required pam_tally2.so")
-		}
-		if err := writeLines(authFile, authLines); err != nil {
-			return err
+		if err := checkAuthStack(rc.readLines(authFile)); err != nil {
+			return fmt.Errorf("%s: %w", authFile, err)
 		}
 	}
 
 	// Verify effective values after write.
-	updated := readFile(loginDefs)
+	updated := rc.readFile(loginDefs)
 	vMax := parseLoginDefsAny(updated, []string{maxKey, "PASS_MAX_DAYS", "MAX_DAYS", "MAX"})
 	vMin := parseLoginDefsAny(updated, []string{minKey, "PASS_MIN_DAYS", "MIN_DAYS", "MIN"})
 	vLen := parseLoginDefsAny(updated, []string{lenKey, "PASS_MIN_LEN", "MIN_LEN", "LEN"})
-	if vMax != "90" || vMin != "1" || toInt(vLen) < 10 {
+	if vMax != maxDays || vMin != minDays || toInt(vLen) < policy.MinLen {
 		return errors.New("login.defs 未按预期更新")
 	}
-	pwMinLen, pwMinClass := readPwqualityConfig()
-	if toInt(pwMinLen) < 10 || toInt(pwMinClass) < 4 {
+	pwMinLen, pwMinClass := readPwqualityConfig(rc)
+	if toInt(pwMinLen) < policy.MinLen || toInt(pwMinClass) < policy.MinClass {
 		return errors.New("pwquality.conf 未按预期更新")
 	}
-	pamContent := readFile(pamFile)
+	pamContent := rc.readFile(pamFile)
 	if parsePamValue(pamContent, "minlen") == "" || parsePamValue(pamContent, "minclass") == "" {
 		return errors.New("PAM 复杂度参数未写入")
 	}
 	return nil
 }
 
-func applyLockScreen() error {
-	config := "/etc/dconf/db/local.d/00-xc-baseline"
-	lockFile := "/etc/dconf/db/local.d/locks/00-xc-baseline"
-	if err := os.MkdirAll(filepath.Dir(lockFile), 0755); err != nil {
-		return err
+func applyLockScreen(rc *RunContext) error {
+	return applyDconfSettings(rc, lockScreenSettings(rc.Profile))
+}
+
+// Remediation values shared by the apply functions and the exporters
+// (fix script, Ansible), so every path writes the same settings.
+
+const (
+	dconfConfigPath = "/etc/dconf/db/local.d/00-xc-baseline"
+	dconfLockPath   = "/etc/dconf/db/local.d/locks/00-xc-baseline"
+	ipv6SysctlPath  = "/etc/sysctl.d/99-xc-baseline.conf"
+
+	dconfMediaHandling = "org/gnome/desktop/media-handling"
+	dconfSession       = "org/gnome/desktop/session"
+	dconfScreensaver   = "org/gnome/desktop/screensaver"
+)
+
+// dconfSetting is one locked key in the site dconf database.
+type dconfSetting struct {
+	Section string
+	Key     string
+	Value   string
+}
+
+func (s dconfSetting) lockPath() string {
+	return "/" + s.Section + "/" + s.Key
+}
+
+func usbAutoplaySettings() []dconfSetting {
+	return []dconfSetting{
+		{dconfMediaHandling, "automount", "false"},
+		{dconfMediaHandling, "automount-open", "false"},
+	}
+}
+
+func lockScreenSettings(p *Profile) []dconfSetting {
+	return []dconfSetting{
+		{dconfSession, "idle-delay", fmt.Sprintf("uint32 %d", p.LockScreen.IdleDelay)},
+		{dconfScreensaver, "lock-enabled", "true"},
+		{dconfScreensaver, "lock-delay", fmt.Sprintf("uint32 %d", p.LockScreen.LockDelay)},
+	}
+}
+
+func applyDconfSettings(rc *RunContext, settings []dconfSetting) error {
+	content := rc.readFile(dconfConfigPath)
+	lockContent := rc.readFile(dconfLockPath)
+	for _, setting := range settings {
+		content = upsertKey(content, setting.Section, setting.Key, setting.Value)
+		lockContent = ensureLock(lockContent, setting.lockPath())
 	}
-	content := readFile(config)
-	content = ensureSection(content, "org/gnome/desktop/session")
-	content = upsertKey(content, "idle-delay", "uint32 900")
-	content = ensureSection(content, "org/gnome/desktop/screensaver")
-	content = upsertKey(content, "lock-enabled", "true")
-	content = upsertKey(content, "lock-delay", "uint32 0")
-	if err := os.WriteFile(config, []byte(content), 0644); err != nil {
+	if err := rc.writeFile(dconfConfigPath, []byte(content), 0644); err != nil {
 		return err
 	}
-	lockContent := readFile(lockFile)
-	lockContent = ensureLock(lockContent, "/org/gnome/desktop/session/idle-delay")
-	lockContent = ensureLock(lockContent, "/org/gnome/desktop/screensaver/lock-enabled")
-	lockContent = ensureLock(lockContent, "/org/gnome/desktop/screensaver/lock-delay")
-	if err := os.WriteFile(lockFile, []byte(lockContent), 0644); err != nil {
+	if err := rc.writeFile(dconfLockPath, []byte(lockContent), 0644); err != nil {
 		return err
 	}
-	if commandExists("dconf") {
-		_, _ = runCommand("dconf", "update")
+	if rc.commandExists("dconf") {
+		_, _ = rc.mutate("dconf", "update")
 	}
 	return nil
 }
 
-func readLines(path string) []string {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return []string{}
+func ipv6SysctlKeys() []string {
+	return []string{
+		"net.ipv6.conf.all.disable_ipv6",
+		"net.ipv6.conf.default.disable_ipv6",
+		"net.ipv6.conf.lo.disable_ipv6",
 	}
-	lines := strings.Split(string(data), "\n")
+}
+
+// remediationAuditRules falls back to a minimal identity watch set when
+// the profile configures no audit rules.
+func remediationAuditRules(p *Profile) []string {
+	if len(p.AuditRules) > 0 {
+		return p.AuditRules
+	}
+	return []string{
+		"-w /etc/passwd -p wa -k identity",
+		"-w /etc/shadow -p wa -k identity",
+		"-w /etc/group -p wa -k identity",
+		"-w /etc/gshadow -p wa -k identity",
+		"-w /etc/sudoers -p wa -k privilege",
+	}
+}
+
+type keyValue struct {
+	Key   string
+	Value string
+}
+
+// pamLine replaces the first live line containing Needle. Otherwise it is
+// inserted at Place: auth modules that must run around pam_unix are
+// anchored to it, the rest are appended.
+type pamLine struct {
+	Needle string
+	Line   string
+	Place  pamPlace
+}
+
+type pamPlace int
+
+const (
+	pamAppend pamPlace = iota
+	pamBeforeUnix
+	pamAfterUnix
+)
+
+func loginDefsSettings(policy PasswordProfile, maxKey, minKey, lenKey string) []keyValue {
+	return []keyValue{
+		{maxKey, fmt.Sprintf("%d", policy.MaxDays)},
+		{minKey, fmt.Sprintf("%d", policy.MinDays)},
+		{lenKey, fmt.Sprintf("%d", policy.MinLen)},
+		{"PASS_WARN_AGE", "7"},
+	}
+}
+
+func pwqualitySettings(policy PasswordProfile) []keyValue {
+	return []keyValue{
+		{"minlen", fmt.Sprintf("%d", policy.MinLen)},
+		{"minclass", fmt.Sprintf("%d", policy.MinClass)},
+	}
+}
+
+func pwqualityPamLine(policy PasswordProfile) string {
+	return fmt.Sprintf("password requisite pam_pwquality.so retry=3 enforce_for_root minlen=%d minclass=%d", policy.MinLen, policy.MinClass)
+}
+
+func unixPamLine(policy PasswordProfile) string {
+	return fmt.Sprintf("password sufficient pam_unix.so sha512 shadow remember=%d use_authtok", policy.Remember)
+}
+
+func faillockPamLines(policy PasswordProfile) []pamLine {
+	return []pamLine{
+		{"pam_faillock.so preauth", fmt.Sprintf("auth required pam_faillock.so preauth silent deny=%d unlock_time=%d", policy.Deny, policy.UnlockTime), pamBeforeUnix},
+		{"pam_faillock.so authfail", fmt.Sprintf("auth [default=die] pam_faillock.so authfail deny=%d unlock_time=%d", policy.Deny, policy.UnlockTime), pamAfterUnix},
+		{"account required pam_faillock.so", "account required pam_faillock.so", pamAppend},
+	}
+}
+
+func tally2PamLines(policy PasswordProfile) []pamLine {
+	return []pamLine{
+		{"auth required pam_tally2.so", fmt.Sprintf("auth required pam_tally2.so deny=%d unlock_time=%d", policy.Deny, policy.UnlockTime), pamBeforeUnix},
+		{"account required pam_tally2.so", "account required pam_tally2.so", pamAppend},
+	}
+}
+
+func splitFileLines(data string) []string {
+	lines := strings.Split(data, "\n")
 	if len(lines) > 0 && lines[len(lines)-1] == "" {
 		lines = lines[:len(lines)-1]
 	}
 	return lines
 }
 
-func writeLines(path string, lines []string) error {
+func writeLines(rc *RunContext, path string, lines []string) error {
 	data := strings.Join(lines, "\n") + "\n"
-	return os.WriteFile(path, []byte(data), 0644)
+	return rc.writeFile(path, []byte(data), 0644)
 }
 
 func upsertKV(lines []string, key, value string) []string {
@@ -1471,72 +2072,263 @@ func upsertKV(lines []string, key, value string) []string {
 	return lines
 }
 
+// upsertKVAllowComment sets every live key line. Only when there is none
+// does it uncomment the first commented-out one, so a file never ends up
+// with several live copies of the key.
 func upsertKVAllowComment(lines []string, key, value string, useEquals bool) []string {
+	format := func(line string) string {
+		sep := "   "
+		if useEquals || strings.Contains(line, "=") {
+			sep = " = "
+		}
+		return fmt.Sprintf("%s%s%s", key, sep, value)
+	}
 	found := false
+	commented := -1
 	for i, line := range lines {
 		trim := strings.TrimSpace(line)
-		trim = strings.TrimPrefix(trim, "#")
-		trim = strings.TrimSpace(trim)
-		if strings.HasPrefix(trim, key) {
-			sep := "   "
-			if useEquals || strings.Contains(line, "=") {
-				sep = " = "
-			}
-			lines[i] = fmt.Sprintf("%s%s%s", key, sep, value)
+		name := strings.TrimSpace(strings.TrimPrefix(trim, "#"))
+		if !hasKVKey(name, key) {
+			continue
+		}
+		if !strings.HasPrefix(trim, "#") {
+			lines[i] = format(line)
+			found = true
+		} else if commented < 0 {
+			commented = i
+		}
+	}
+	switch {
+	case found:
+	case commented >= 0:
+		lines[commented] = format(lines[commented])
+	default:
+		lines = append(lines, format(""))
+	}
+	return lines
+}
+
+// hasKVKey reports whether line starts with exactly key, not a longer key
+// sharing its prefix.
+func hasKVKey(line, key string) bool {
+	if !strings.HasPrefix(line, key) {
+		return false
+	}
+	rest := line[len(key):]
+	return rest == "" || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '='
+}
+
+func ensurePamLine(lines []string, pl pamLine) ([]string, error) {
+	for i, existing := range lines {
+		if pamLive(existing) && strings.Contains(existing, pl.Needle) {
+			lines[i] = pl.Line
+			return lines, nil
+		}
+	}
+	if pl.Place == pamAppend {
+		return append(lines, pl.Line), nil
+	}
+	unix := findPamModule(lines, "auth", "pam_unix.so")
+	if unix < 0 {
+		return nil, fmt.Errorf("未找到 auth pam_unix.so 行，无法定位 %s", pl.Needle)
+	}
+	if pl.Place == pamAfterUnix {
+		unix++
+	}
+	return insertPamLine(lines, unix, pl.Line), nil
+}
+
+// ensurePasswordPamLines sets remember=N on the pam_unix password line,
+// keeping the distro's control and hash options, or inserts a full line
+// before pam_deny when there is none. pam_pwquality is kept ahead of
+// pam_unix so use_authtok has a token to use.
+func ensurePasswordPamLines(lines []string, policy PasswordProfile) ([]string, error) {
+	if i := findPamModule(lines, "password", "pam_unix.so"); i >= 0 {
+		if policy.Remember > 0 {
+			lines[i] = setPamArg(lines[i], "remember", fmt.Sprintf("%d", policy.Remember))
+		}
+	} else {
+		deny := findPamModule(lines, "password", "pam_deny.so")
+		if deny < 0 {
+			return nil, errors.New("未找到 password pam_unix.so 或 pam_deny.so 行，无法定位 pam_unix")
+		}
+		lines = insertPamLine(lines, deny, unixPamLine(policy))
+	}
+	found := false
+	for i, line := range lines {
+		if pamLive(line) && (strings.Contains(line, "pam_pwquality.so") || strings.Contains(line, "pam_cracklib.so")) {
+			lines[i] = pwqualityPamLine(policy)
 			found = true
 		}
 	}
 	if !found {
-		sep := "   "
-		if useEquals {
-			sep = " = "
+		lines = insertPamLine(lines, findPamModule(lines, "password", "pam_unix.so"), pwqualityPamLine(policy))
+	}
+	return lines, nil
+}
+
+// setPamArg sets key=value among a PAM line's module arguments.
+func setPamArg(line, key, value string) string {
+	re := regexp.MustCompile(`(\s)` + regexp.QuoteMeta(key) + `=\S*`)
+	if re.MatchString(line) {
+		return re.ReplaceAllString(line, "${1}"+key+"="+value)
+	}
+	return strings.TrimRight(line, " \t") + " " + key + "=" + value
+}
+
+func pamLive(line string) bool {
+	trim := strings.TrimSpace(line)
+	return trim != "" && !strings.HasPrefix(trim, "#")
+}
+
+// pamFields splits a live PAM line into type (without the leading "-"),
+// control (a bracketed control is kept whole) and module base name.
+func pamFields(line string) (typ, control, module string) {
+	if !pamLive(line) {
+		return "", "", ""
+	}
+	rest := strings.TrimSpace(line)
+	fields := strings.Fields(rest)
+	typ = strings.TrimPrefix(fields[0], "-")
+	rest = strings.TrimSpace(rest[len(fields[0]):])
+	if strings.HasPrefix(rest, "[") {
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			return typ, "", ""
 		}
-		lines = append(lines, fmt.Sprintf("%s%s%s", key, sep, value))
+		control, rest = rest[:end+1], rest[end+1:]
+	} else if fields := strings.Fields(rest); len(fields) > 0 {
+		control, rest = fields[0], rest[len(fields[0]):]
 	}
-	return lines
+	if fields := strings.Fields(rest); len(fields) > 0 {
+		module = filepath.Base(fields[0])
+	}
+	return typ, control, module
 }
 
-func writeProcValue(path, value string) {
-	_ = os.WriteFile(path, []byte(value), 0644)
+func findPamModule(lines []string, typ, module string) int {
+	for i, line := range lines {
+		if t, _, m := pamFields(line); t == typ && m == module {
+			return i
+		}
+	}
+	return -1
 }
 
-func ensurePamLine(lines []string, needle string, line string) []string {
+var pamJump = regexp.MustCompile(`(\w+)=(\d+)`)
+
+// insertPamLine inserts line before lines[at]. Jumps of earlier modules of
+// the same type (Debian's [success=N ...]) that skip over the insertion
+// point are widened by one so they still land on the same module.
+func insertPamLine(lines []string, at int, line string) []string {
+	typ, _, _ := pamFields(line)
+	var stack []int
 	for i, existing := range lines {
-		if strings.Contains(existing, needle) {
-			lines[i] = line
-			return lines
+		if t, _, _ := pamFields(existing); t == typ {
+			stack = append(stack, i)
+		}
+	}
+	pos := len(stack)
+	for p, i := range stack {
+		if i >= at {
+			pos = p
+			break
 		}
 	}
-	return append(lines, line)
+	for p := 0; p < pos; p++ {
+		i := stack[p]
+		_, control, _ := pamFields(lines[i])
+		if !strings.HasPrefix(control, "[") {
+			continue
+		}
+		widened := pamJump.ReplaceAllStringFunc(control, func(kv string) string {
+			m := pamJump.FindStringSubmatch(kv)
+			if n := toInt(m[2]); pos <= p+n {
+				return fmt.Sprintf("%s=%d", m[1], n+1)
+			}
+			return kv
+		})
+		lines[i] = strings.Replace(lines[i], control, widened, 1)
+	}
+	out := make([]string, 0, len(lines)+1)
+	out = append(out, lines[:at]...)
+	out = append(out, line)
+	return append(out, lines[at:]...)
 }
 
-func pamModuleExists(module string) bool {
-	paths := []string{
-		"/lib/security/" + module,
-		"/lib64/security/" + module,
-		"/lib/x86_64-linux-gnu/security/" + module,
-		"/usr/lib/security/" + module,
-		"/usr/lib64/security/" + module,
+// checkAuthStack rejects an auth stack that would deny a correct password:
+// pam_unix must exist, nothing before it may deny outright, and the module
+// reached after pam_unix succeeds must not be pam_deny or [default=die].
+func checkAuthStack(lines []string) error {
+	type entry struct{ control, module string }
+	var stack []entry
+	for _, line := range lines {
+		if t, control, module := pamFields(line); t == "auth" {
+			stack = append(stack, entry{control, module})
+		}
 	}
-	for _, path := range paths {
-		if _, err := os.Stat(path); err == nil {
+	denies := func(e entry) bool {
+		return e.module == "pam_deny.so" || strings.Contains(e.control, "default=die")
+	}
+	unix := -1
+	for i, e := range stack {
+		if e.module == "pam_unix.so" {
+			unix = i
+			break
+		}
+		if denies(e) {
+			return fmt.Errorf("认证栈中 %s 位于 pam_unix.so 之前", e.module)
+		}
+	}
+	if unix < 0 {
+		return errors.New("认证栈中缺少 pam_unix.so")
+	}
+	control := stack[unix].control
+	next := unix + 1
+	if control == "sufficient" || strings.Contains(control, "success=done") {
+		return nil
+	}
+	for _, m := range pamJump.FindAllStringSubmatch(control, -1) {
+		if m[1] == "success" {
+			next += toInt(m[2])
+		}
+	}
+	if next < len(stack) && denies(stack[next]) {
+		return fmt.Errorf("pam_unix.so 认证成功后将执行 %s，正确密码也会被拒绝", stack[next].module)
+	}
+	return nil
+}
+
+var pamModuleDirs = []string{
+	"/lib/security",
+	"/lib64/security",
+	"/lib/x86_64-linux-gnu/security",
+	"/usr/lib/security",
+	"/usr/lib64/security",
+}
+
+func pamModuleExists(rc *RunContext, module string) bool {
+	for _, dir := range pamModuleDirs {
+		if rc.fileExists(dir + "/" + module) {
 			return true
 		}
 	}
 	return false
 }
 
-func readProcValue(path string) string {
-	data, err := os.ReadFile(path)
+func readProcValue(rc *RunContext, path string) string {
+	data, err := rc.Sys.ReadFile(path)
 	if err != nil {
 		return ""
 	}
 	return strings.TrimSpace(string(data))
 }
 
-func replaceOrAppendKV(path, key, value string, useEquals bool) error {
-	lines := readLines(path)
-	re := regexp.MustCompile(`(?i)^\s*#?\s*` + regexp.QuoteMeta(key) + `\b.*`)
+// replaceOrAppendKV sets key on every active line that defines it and
+// leaves commented lines alone; without an active line the key is appended.
+func replaceOrAppendKV(rc *RunContext, path, key, value string, useEquals bool) error {
+	lines := rc.readLines(path)
+	re := regexp.MustCompile(`(?i)^\s*` + regexp.QuoteMeta(key) + `(\s|=|$)`)
 	replaced := false
 	for i, line := range lines {
 		if re.MatchString(line) {
@@ -1555,39 +2347,93 @@ func replaceOrAppendKV(path, key, value string, useEquals bool) error {
 		}
 		lines = append(lines, fmt.Sprintf("%s%s%s", key, sep, value))
 	}
-	return writeLines(path, lines)
+	return writeLines(rc, path, lines)
+}
+
+// upsertKey sets key=value inside [section] of a keyfile such as a dconf
+// database, like set_ini in the fix script: the first key line in the
+// section is replaced and later duplicates dropped, a missing key goes after
+// the section's last line and a missing section is appended. The same key
+// in other sections is left alone.
+func upsertKey(content, section, key, value string) string {
+	lines := splitFileLines(content)
+	keyRe := keyfileKeyRe(key)
+	setting := fmt.Sprintf("%s=%s", key, value)
+	out := make([]string, 0, len(lines)+3)
+	inSection, done := false, false
+	last := -1
+	for _, line := range lines {
+		if header, ok := keyfileSection(line); ok {
+			if inSection && !done {
+				out = insertAt(out, last+1, setting)
+				done = true
+			}
+			inSection = header == section
+			out = append(out, line)
+			last = len(out) - 1
+			continue
+		}
+		if inSection && keyRe.MatchString(line) {
+			if !done {
+				out = append(out, setting)
+				done = true
+			}
+			continue
+		}
+		out = append(out, line)
+		if inSection && strings.TrimSpace(line) != "" {
+			last = len(out) - 1
+		}
+	}
+	switch {
+	case done:
+	case inSection:
+		out = insertAt(out, last+1, setting)
+	default:
+		if len(out) > 0 {
+			out = append(out, "")
+		}
+		out = append(out, "["+section+"]", setting)
+	}
+	return strings.Join(out, "\n") + "\n"
 }
 
-func readFile(path string) string {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return ""
+// keyfileValue returns the value of key in [section] and its 1-based line,
+// or "" and 0. A later line wins, as it does when dconf compiles the file.
+func keyfileValue(content, section, key string) (string, int) {
+	keyRe := keyfileKeyRe(key)
+	value, found := "", 0
+	current := ""
+	for i, line := range strings.Split(content, "\n") {
+		if header, ok := keyfileSection(line); ok {
+			current = header
+			continue
+		}
+		if current == section && keyRe.MatchString(line) {
+			value = strings.TrimSpace(line[strings.Index(line, "=")+1:])
+			found = i + 1
+		}
 	}
-	return string(data)
+	return value, found
 }
 
-func ensureSection(content, section string) string {
-	marker := "[" + section + "]"
-	if strings.Contains(content, marker) {
-		return content
+func keyfileSection(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+		return trimmed[1 : len(trimmed)-1], true
 	}
-	if !strings.HasSuffix(content, "\n") && content != "" {
-		content += "\n"
-	}
-	content += "\n" + marker + "\n"
-	return content
+	return "", false
 }
 
-func upsertKey(content, key, value string) string {
-	lines := strings.Split(content, "\n")
-	for i, line := range lines {
-		if strings.HasPrefix(strings.TrimSpace(line), key+"=") {
-			lines[i] = fmt.Sprintf("%s=%s", key, value)
-			return strings.Join(lines, "\n")
-		}
-	}
-	lines = append(lines, fmt.Sprintf("%s=%s", key, value))
-	return strings.Join(lines, "\n")
+func keyfileKeyRe(key string) *regexp.Regexp {
+	return regexp.MustCompile(`^\s*` + regexp.QuoteMeta(key) + `\s*=`)
+}
+
+func insertAt(lines []string, at int, line string) []string {
+	lines = append(lines, "")
+	copy(lines[at+1:], lines[at:])
+	lines[at] = line
+	return lines
 }
 
 func ensureLock(content, key string) string {
@@ -1647,9 +2493,8 @@ func colorize(color, text string) string {
 	}
 }
 
-func manualFixHint(id string) string {
-	info := detectOSInfo()
-	kind := detectDistroKind(info)
+func manualFixHint(rc *RunContext, id string) string {
+	kind := rc.Distro
 	guiPrefix := "系统设置"
 	if kind.IsUOS {
 		guiPrefix = "控制中心"
@@ -1659,10 +2504,10 @@ func manualFixHint(id string) string {
 		return "在“服务管理”中停用 FTP 服务（vsftpd/proftpd 等）"
 	case "risky_ports":
 		if kind.IsUOS {
-			return "UOS建议使用UFW: " + pkgInstallCmd("ufw") + "（未安装时）。示例: sudo ufw default deny incoming; sudo ufw allow 80/tcp; sudo ufw allow 443/tcp; sudo ufw deny 21/tcp 3389/tcp 445/tcp; sudo ufw enable; sudo ufw status verbose。或使用iptables: sudo iptables -A INPUT -p tcp --dport 21 -j DROP 等。"
+			return "UOS建议使用UFW: " + pkgInstallCmd(rc, "ufw") + "（未安装时）。示例: sudo ufw default deny incoming; sudo ufw allow 80/tcp; sudo ufw allow 443/tcp; sudo ufw deny 21/tcp 3389/tcp 445/tcp; sudo ufw enable; sudo ufw status verbose。或使用iptables: sudo iptables -A INPUT -p tcp --dport 21 -j DROP 等。"
 		}
 		if kind.IsKylin || kind.IsNeoKylin {
-			return "麒麟建议使用iptables或firewalld。iptables示例: sudo iptables -A INPUT -p tcp --dport 139 -j DROP; sudo iptables -A INPUT -p tcp --dport 445 -j DROP。firewalld示例: sudo systemctl enable --now firewalld; sudo firewall-cmd --zone=public --remove-port=139/tcp --permanent; sudo firewall-cmd --zone=public --remove-port=445/tcp --permanent; sudo firewall-cmd --reload。未安装防火墙时: " + pkgInstallCmd("firewalld") + "。"
+			return "麒麟建议使用iptables或firewalld。iptables示例: sudo iptables -A INPUT -p tcp --dport 139 -j DROP; sudo iptables -A INPUT -p tcp --dport 445 -j DROP。firewalld示例: sudo systemctl enable --now firewalld; sudo firewall-cmd --zone=public --remove-port=139/tcp --permanent; sudo firewall-cmd --zone=public --remove-port=445/tcp --permanent; sudo firewall-cmd --reload。未安装防火墙时: " + pkgInstallCmd(rc, "firewalld") + "。"
 		}
 		return guiPrefix + " → 防火墙 → 端口规则 → 添加拒绝规则"
 	case "usb_autoplay":
@@ -1691,26 +2536,25 @@ func manualFixHint(id string) string {
 		return guiPrefix + " → 更新管理 → 检查更新 → 安装更新"
 	case "password_policy":
 		if kind.IsUOS {
-			return guiPrefix + " → 账户与安全 → 密码策略 → 长度>=10/复杂度>=4/历史5次/锁定"
+			pw := rc.Profile.Password
+			return fmt.Sprintf("%s → 账户与安全 → 密码策略 → 长度>=%d/复杂度>=%d/历史%d次/锁定", guiPrefix, pw.MinLen, pw.MinClass, pw.Remember)
 		}
 		if kind.IsKylin || kind.IsNeoKylin {
 			return guiPrefix + " → 安全中心 → 账户策略 → 密码复杂度与锁定策略"
 		}
 		return guiPrefix + " → 账户策略 → 密码复杂度与锁定策略"
 	case "lock_screen":
+		delay := idleDelayText(rc.Profile.LockScreen.IdleDelay)
 		if kind.IsUOS {
-			return guiPrefix + " → 个性化 → 锁屏 → 开启锁屏并设置 15 分钟内自动锁定"
+			return guiPrefix + " → 个性化 → 锁屏 → 开启锁屏并设置 " + delay + "内自动锁定"
 		}
-		if kind.IsKylin || kind.IsNeoKylin {
-			return guiPrefix + " → 个性化 → 锁屏 → 开启并设置 15 分钟内自动锁定"
-		}
-		return guiPrefix + " → 个性化 → 锁屏 → 开启并设置 15 分钟内自动锁定"
+		return guiPrefix + " → 个性化 → 锁屏 → 开启并设置 " + delay + "内自动锁定"
 	case "audit_rules":
 		if kind.IsUOS {
-			return "安装与启用审计服务: " + pkgInstallCmd("auditd") + "；systemctl enable --now auditd；在 /etc/audit/rules.d/ 下配置规则并执行 augenrules --load"
+			return "安装与启用审计服务: " + pkgInstallCmd(rc, "auditd") + "；systemctl enable --now auditd；在 /etc/audit/rules.d/ 下配置规则并执行 augenrules --load"
 		}
 		if kind.IsKylin || kind.IsNeoKylin {
-			return "安装与启用审计服务: " + pkgInstallCmd("audit") + "；systemctl enable --now auditd；在 /etc/audit/rules.d/ 下配置规则并执行 augenrules --load"
+			return "安装与启用审计服务: " + pkgInstallCmd(rc, "audit") + "；systemctl enable --now auditd；在 /etc/audit/rules.d/ 下配置规则并执行 augenrules --load"
 		}
 		return "安装并启用 auditd，配置 /etc/audit/rules.d/*.rules 并执行 augenrules --load"
 	default:
@@ -1718,8 +2562,20 @@ func manualFixHint(id string) string {
 	}
 }
 
-func detectOSInfo() OSInfo {
-	data, err := os.ReadFile("/etc/os-release")
+// idleDelayText renders a lock-screen delay in seconds as GUI settings
+// show it: whole minutes where possible.
+func idleDelayText(seconds int) string {
+	if seconds >= 60 && seconds%60 == 0 {
+		return fmt.Sprintf("%d 分钟", seconds/60)
+	}
+	return fmt.Sprintf("%d 秒", seconds)
+}
+
+func detectOSInfo(sys System) OSInfo {
+	data, err := sys.ReadFile("/etc/os-release")
+	if err != nil {
+		data, err = sys.ReadFile("/usr/lib/os-release")
+	}
 	if err != nil {
 		return OSInfo{}
 	}
@@ -1765,8 +2621,8 @@ func trimOSValue(line string) string {
 	return strings.Trim(parts[1], "\"")
 }
 
-func patchFixHint() string {
-	switch detectPkgManager() {
+func patchFixHint(rc *RunContext) string {
+	switch detectPkgManager(rc) {
 	case "apt":
 		return "apt-get update && apt-get upgrade"
 	case "dnf":
@@ -1778,38 +2634,38 @@ func patchFixHint() string {
 	}
 }
 
-func detectPkgManager() string {
-	if commandExists("apt-get") {
+func detectPkgManager(rc *RunContext) string {
+	if rc.commandExists("apt-get") {
 		return "apt"
 	}
-	if commandExists("dnf") {
+	if rc.commandExists("dnf") {
 		return "dnf"
 	}
-	if commandExists("yum") {
+	if rc.commandExists("yum") {
 		return "yum"
 	}
 	return ""
 }
 
-func firewallFixHint() string {
-	if commandExists("firewall-cmd") {
+func firewallFixHint(rc *RunContext) string {
+	if rc.commandExists("firewall-cmd") {
 		return "firewalld：in/out 规则（参见README示例）"
 	}
-	if commandExists("nft") {
+	if rc.commandExists("nft") {
 		return "nftables：input/output 链封禁 dport"
 	}
-	if commandExists("iptables") {
+	if rc.commandExists("iptables") {
 		return "iptables：INPUT/OUTPUT 链 DROP/REJECT"
 	}
 	return "未检测到防火墙组件，请联系管理员"
 }
 
-func firewallInstallHint() string {
-	return pkgInstallCmd("firewalld")
+func firewallInstallHint(rc *RunContext) string {
+	return pkgInstallCmd(rc, "firewalld")
 }
 
-func pkgInstallCmd(pkg string) string {
-	switch detectPkgManager() {
+func pkgInstallCmd(rc *RunContext, pkg string) string {
+	switch detectPkgManager(rc) {
 	case "apt":
 		return "sudo apt install -y " + pkg
 	case "dnf":
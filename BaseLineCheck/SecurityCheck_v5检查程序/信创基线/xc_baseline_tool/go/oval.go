@@ -0,0 +1,1181 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// --oval loads vendor OVAL 5 definitions as extra items in the "oval"
+// category. Only the common test types are evaluated, all through rc.Sys
+// so --root and --replay behave as for built-in items:
+// textfilecontent54, sysctl, rpminfo, dpkginfo, family and variable.
+// Definitions using any other test come out as manual.
+
+// ovalNode is a generic element; tests, objects and states come from
+// several schema namespaces and are interpreted by local name.
+type ovalNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Nodes   []ovalNode `xml:",any"`
+	Text    string     `xml:",chardata"`
+}
+
+func (n *ovalNode) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (n *ovalNode) child(name string) *ovalNode {
+	for i := range n.Nodes {
+		if n.Nodes[i].XMLName.Local == name {
+			return &n.Nodes[i]
+		}
+	}
+	return nil
+}
+
+func (n *ovalNode) childText(name string) string {
+	if c := n.child(name); c != nil {
+		return strings.TrimSpace(c.Text)
+	}
+	return ""
+}
+
+type ovalDocument struct {
+	XMLName     xml.Name `xml:"oval_definitions"`
+	Definitions ovalNode `xml:"definitions"`
+	Tests       ovalNode `xml:"tests"`
+	Objects     ovalNode `xml:"objects"`
+	States      ovalNode `xml:"states"`
+	Variables   ovalNode `xml:"variables"`
+}
+
+type ovalContent struct {
+	definitions map[string]*ovalNode
+	tests       map[string]*ovalNode
+	objects     map[string]*ovalNode
+	states      map[string]*ovalNode
+	variables   map[string]*ovalNode
+}
+
+func indexOVAL(section *ovalNode) map[string]*ovalNode {
+	out := map[string]*ovalNode{}
+	for i := range section.Nodes {
+		if id := section.Nodes[i].attr("id"); id != "" {
+			out[id] = &section.Nodes[i]
+		}
+	}
+	return out
+}
+
+// registerOVAL parses every file before registering anything, so a broken
+// file leaves the catalog unchanged.
+func registerOVAL(paths []string) error {
+	metas := []CheckMeta{}
+	checks := []func(rc *RunContext) Result{}
+	seen := map[string]string{}
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		var doc ovalDocument
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("%s: %v", p, err)
+		}
+		c := &ovalContent{
+			definitions: indexOVAL(&doc.Definitions),
+			tests:       indexOVAL(&doc.Tests),
+			objects:     indexOVAL(&doc.Objects),
+			states:      indexOVAL(&doc.States),
+			variables:   indexOVAL(&doc.Variables),
+		}
+		for i := range doc.Definitions.Nodes {
+			def := &doc.Definitions.Nodes[i]
+			defID := def.attr("id")
+			if def.XMLName.Local != "definition" || defID == "" || def.attr("deprecated") == "true" {
+				continue
+			}
+			meta := c.meta(def)
+			if _, ok := findChecker(meta.ID); ok {
+				return fmt.Errorf("%s: 定义 %s 与内置检查项 %s 重名", p, defID, meta.ID)
+			}
+			if prev, ok := seen[meta.ID]; ok {
+				return fmt.Errorf("%s: 定义 %s 与 %s 的检查项ID %s 重复", p, defID, prev, meta.ID)
+			}
+			seen[meta.ID] = defID
+			metas = append(metas, meta)
+			checks = append(checks, func(rc *RunContext) Result { return c.check(rc, defID) })
+		}
+	}
+	if len(metas) == 0 {
+		return errors.New("未找到可用的 OVAL 定义")
+	}
+	for i := range metas {
+		registerFunc(metas[i], checks[i])
+	}
+	return nil
+}
+
+var ovalIDUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// ovalItemID turns oval:cn.vendor:def:1001 into oval_cn.vendor_1001.
+func ovalItemID(defID string) string {
+	parts := strings.Split(defID, ":")
+	if len(parts) == 4 && parts[0] == "oval" && parts[2] == "def" {
+		return "oval_" + ovalIDUnsafe.ReplaceAllString(parts[1], "_") + "_" + ovalIDUnsafe.ReplaceAllString(parts[3], "_")
+	}
+	return "oval_" + ovalIDUnsafe.ReplaceAllString(defID, "_")
+}
+
+// Vulnerability and patch definitions describe the bad state: true fails.
+func ovalTrueFails(class string) bool {
+	return class == "vulnerability" || class == "patch"
+}
+
+func (c *ovalContent) meta(def *ovalNode) CheckMeta {
+	defID := def.attr("id")
+	meta := CheckMeta{
+		ID:       ovalItemID(defID),
+		Name:     defID,
+		Category: "oval",
+		Severity: "medium",
+		Expected: "OVAL 定义 " + defID + " 评估为 true",
+	}
+	if ovalTrueFails(def.attr("class")) {
+		meta.Expected = "OVAL 定义 " + defID + " 评估为 false（不存在所述问题）"
+	}
+	if md := def.child("metadata"); md != nil {
+		if title := md.childText("title"); title != "" {
+			meta.Name = title
+		}
+		meta.Desc = strings.Join(strings.Fields(md.childText("description")), " ")
+		if advisory := md.child("advisory"); advisory != nil {
+			switch strings.ToLower(advisory.childText("severity")) {
+			case "critical", "important", "high":
+				meta.Severity = "high"
+			case "low":
+				meta.Severity = "low"
+			}
+		}
+	}
+	// sysctl reads the running kernel and rpminfo needs the rpm command.
+	for _, kind := range c.testKinds(def, map[string]bool{}) {
+		if kind == "sysctl" || kind == "rpminfo" {
+			meta.Live = true
+		}
+	}
+	return meta
+}
+
+func (c *ovalContent) testKinds(def *ovalNode, visited map[string]bool) []string {
+	if def == nil || visited[def.attr("id")] {
+		return nil
+	}
+	visited[def.attr("id")] = true
+	kinds := []string{}
+	var walk func(n *ovalNode)
+	walk = func(n *ovalNode) {
+		for i := range n.Nodes {
+			child := &n.Nodes[i]
+			switch child.XMLName.Local {
+			case "criteria":
+				walk(child)
+			case "criterion":
+				if test := c.tests[child.attr("test_ref")]; test != nil {
+					kinds = append(kinds, strings.TrimSuffix(test.XMLName.Local, "_test"))
+				}
+			case "extend_definition":
+				kinds = append(kinds, c.testKinds(c.definitions[child.attr("definition_ref")], visited)...)
+			}
+		}
+	}
+	if criteria := def.child("criteria"); criteria != nil {
+		walk(criteria)
+	}
+	return kinds
+}
+
+type ovalResult int
+
+const (
+	ovalTrue ovalResult = iota
+	ovalFalse
+	ovalError
+	ovalUnknown
+	ovalNotEvaluated
+	ovalNotApplicable
+)
+
+func (r ovalResult) String() string {
+	return [...]string{"true", "false", "error", "unknown", "not evaluated", "not applicable"}[r]
+}
+
+func (r ovalResult) negate() ovalResult {
+	switch r {
+	case ovalTrue:
+		return ovalFalse
+	case ovalFalse:
+		return ovalTrue
+	}
+	return r
+}
+
+func ovalBool(ok bool) ovalResult {
+	if ok {
+		return ovalTrue
+	}
+	return ovalFalse
+}
+
+// combineOVAL applies the OVAL operator truth tables. Not applicable
+// results are ignored; error, unknown and not evaluated win in that order
+// when the determined results do not settle the outcome.
+func combineOVAL(operator string, results []ovalResult) ovalResult {
+	n := map[ovalResult]int{}
+	for _, r := range results {
+		n[r]++
+	}
+	undetermined := func() ovalResult {
+		for _, r := range []ovalResult{ovalError, ovalUnknown, ovalNotEvaluated} {
+			if n[r] > 0 {
+				return r
+			}
+		}
+		return ovalNotApplicable
+	}
+	determined := n[ovalTrue] + n[ovalFalse]
+	open := n[ovalError] + n[ovalUnknown] + n[ovalNotEvaluated]
+	switch operator {
+	case "OR":
+		if n[ovalTrue] > 0 {
+			return ovalTrue
+		}
+		if open == 0 && determined > 0 {
+			return ovalFalse
+		}
+	case "ONE":
+		if n[ovalTrue] > 1 {
+			return ovalFalse
+		}
+		if open == 0 && determined > 0 {
+			return ovalBool(n[ovalTrue] == 1)
+		}
+	case "XOR":
+		if open == 0 && determined > 0 {
+			return ovalBool(n[ovalTrue]%2 == 1)
+		}
+	default: // AND
+		if n[ovalFalse] > 0 {
+			return ovalFalse
+		}
+		if open == 0 && determined > 0 {
+			return ovalTrue
+		}
+	}
+	return undetermined()
+}
+
+type ovalItem struct {
+	fields   map[string][]string
+	observed string
+}
+
+// ovalTestDetail is what a test contributes to the item's findings.
+type ovalTestDetail struct {
+	label    string
+	observed string
+	expected string
+	source   *Evidence
+	err      error
+}
+
+type ovalEval struct {
+	rc          *RunContext
+	c           *ovalContent
+	results     map[string]ovalResult
+	details     map[string]*ovalTestDetail
+	visiting    map[string]bool
+	want        bool
+	findings    []Finding
+	findingSeen map[string]bool
+	unsupported []string
+	errs        []string
+}
+
+func (c *ovalContent) check(rc *RunContext, defID string) Result {
+	def := c.definitions[defID]
+	e := &ovalEval{
+		rc:          rc,
+		c:           c,
+		results:     map[string]ovalResult{},
+		details:     map[string]*ovalTestDetail{},
+		visiting:    map[string]bool{},
+		want:        !ovalTrueFails(def.attr("class")),
+		findingSeen: map[string]bool{},
+	}
+	r := e.definition(defID)
+	current := fmt.Sprintf("OVAL 定义 %s 评估结果: %s", defID, r)
+	if len(e.unsupported) > 0 {
+		sort.Strings(e.unsupported)
+		current += " | 不支持的测试类型: " + strings.Join(e.unsupported, ", ")
+	}
+	if len(e.errs) > 0 {
+		current += " | " + strings.Join(e.errs, "; ")
+	}
+	res := Result{Current: current, Findings: e.findings}
+	switch r {
+	case ovalTrue, ovalFalse:
+		res.Status = passFail((r == ovalTrue) == e.want)
+	case ovalError:
+		res.Status = "error"
+	case ovalNotApplicable:
+		res.Status = "not_applicable"
+	default:
+		res.Status = "manual"
+	}
+	// A false test under an OR does not make a passing definition fail.
+	if res.Status == "pass" {
+		for i := range res.Findings {
+			if res.Findings[i].Status != "pass" {
+				res.Findings[i].Status = "info"
+			}
+		}
+	}
+	return res
+}
+
+func (e *ovalEval) definition(id string) ovalResult {
+	def := e.c.definitions[id]
+	if def == nil {
+		e.errs = append(e.errs, "未定义的 "+id)
+		return ovalError
+	}
+	if e.visiting[id] {
+		e.errs = append(e.errs, "定义循环引用 "+id)
+		return ovalError
+	}
+	e.visiting[id] = true
+	defer delete(e.visiting, id)
+	criteria := def.child("criteria")
+	if criteria == nil {
+		return ovalUnknown
+	}
+	r := e.criteria(criteria)
+	if criteria.attr("negate") == "true" {
+		r = r.negate()
+	}
+	return r
+}
+
+func (e *ovalEval) criteria(n *ovalNode) ovalResult {
+	results := []ovalResult{}
+	for i := range n.Nodes {
+		child := &n.Nodes[i]
+		var r ovalResult
+		switch child.XMLName.Local {
+		case "criteria":
+			r = e.criteria(child)
+		case "criterion":
+			r = e.test(child.attr("test_ref"))
+		case "extend_definition":
+			r = e.definition(child.attr("definition_ref"))
+		default:
+			continue
+		}
+		if child.attr("negate") == "true" {
+			r = r.negate()
+		}
+		if child.XMLName.Local == "criterion" {
+			e.addFinding(child.attr("test_ref"), r)
+		}
+		results = append(results, r)
+	}
+	return combineOVAL(n.attr("operator"), results)
+}
+
+func (e *ovalEval) addFinding(testID string, r ovalResult) {
+	d := e.details[testID]
+	if d == nil || e.findingSeen[testID] {
+		return
+	}
+	e.findingSeen[testID] = true
+	f := Finding{Key: testID, Label: d.label, Observed: d.observed, Expected: d.expected, Source: d.source}
+	switch r {
+	case ovalTrue, ovalFalse:
+		f.Status = passFail((r == ovalTrue) == e.want)
+	case ovalError:
+		f.Status = "error"
+	case ovalNotApplicable:
+		f.Status = "info"
+	default:
+		f.Status = "manual"
+	}
+	e.findings = append(e.findings, f)
+}
+
+func (e *ovalEval) test(id string) ovalResult {
+	if r, ok := e.results[id]; ok {
+		return r
+	}
+	r := e.evalTest(id)
+	e.results[id] = r
+	if d := e.details[id]; d != nil && d.err != nil {
+		e.errs = append(e.errs, d.err.Error())
+	}
+	return r
+}
+
+func (e *ovalEval) evalTest(id string) ovalResult {
+	test := e.c.tests[id]
+	if test == nil {
+		e.errs = append(e.errs, "未定义的 "+id)
+		return ovalError
+	}
+	kind := strings.TrimSuffix(test.XMLName.Local, "_test")
+	d := &ovalTestDetail{label: valueOr(test.attr("comment"), kind)}
+	e.details[id] = d
+	collect, ok := ovalCollectors[kind]
+	if !ok {
+		if !containsString(e.unsupported, kind) {
+			e.unsupported = append(e.unsupported, kind)
+		}
+		d.observed = "不支持的测试类型 " + kind
+		d.expected = "需人工确认"
+		return ovalNotEvaluated
+	}
+	var object *ovalNode
+	if ref := test.child("object"); ref != nil {
+		object = e.c.objects[ref.attr("object_ref")]
+	}
+	if object == nil {
+		d.err = fmt.Errorf("%s: 未找到对象", id)
+		return ovalError
+	}
+	states := []*ovalNode{}
+	expected := []string{}
+	for i := range test.Nodes {
+		if ref := &test.Nodes[i]; ref.XMLName.Local == "state" {
+			state := e.c.states[ref.attr("state_ref")]
+			if state == nil {
+				d.err = fmt.Errorf("%s: 未找到状态 %s", id, ref.attr("state_ref"))
+				return ovalError
+			}
+			states = append(states, state)
+			expected = append(expected, e.describeState(state))
+		}
+	}
+	d.expected = valueOr(strings.Join(expected, "; "), "对象存在")
+	items, source, err := collect(e, object)
+	d.source = source
+	if err != nil {
+		d.err = fmt.Errorf("%s: %v", id, err)
+		d.observed = err.Error()
+		return ovalError
+	}
+	observed := []string{}
+	for i, item := range items {
+		if i == 3 {
+			observed = append(observed, fmt.Sprintf("等%d项", len(items)))
+			break
+		}
+		observed = append(observed, item.observed)
+	}
+	d.observed = valueOr(strings.Join(observed, "; "), "未找到对象")
+
+	existence := test.attr("check_existence")
+	count := len(items)
+	switch existence {
+	case "none_exist":
+		d.expected = "对象不存在"
+		return ovalBool(count == 0)
+	case "any_exist":
+	case "only_one_exists":
+		if count != 1 {
+			d.expected = "恰好存在一个对象"
+			return ovalFalse
+		}
+	default: // at_least_one_exists, all_exist
+		if count == 0 {
+			d.expected = "对象存在"
+			return ovalFalse
+		}
+	}
+	if len(states) == 0 {
+		return ovalTrue
+	}
+	itemResults := []ovalResult{}
+	for _, item := range items {
+		perState := []ovalResult{}
+		for _, state := range states {
+			perState = append(perState, e.matchState(item, state, d))
+		}
+		itemResults = append(itemResults, combineOVAL(valueOr(test.attr("state_operator"), "AND"), perState))
+	}
+	switch test.attr("check") {
+	case "at least one":
+		if len(itemResults) == 0 {
+			return ovalFalse
+		}
+		return combineOVAL("OR", itemResults)
+	case "only one":
+		if len(itemResults) == 0 {
+			return ovalFalse
+		}
+		return combineOVAL("ONE", itemResults)
+	case "none satisfy":
+		if len(itemResults) == 0 {
+			return ovalTrue
+		}
+		for i := range itemResults {
+			itemResults[i] = itemResults[i].negate()
+		}
+		return combineOVAL("AND", itemResults)
+	default: // all
+		if len(itemResults) == 0 {
+			return ovalTrue
+		}
+		return combineOVAL("AND", itemResults)
+	}
+}
+
+func (e *ovalEval) describeState(state *ovalNode) string {
+	parts := []string{}
+	for i := range state.Nodes {
+		entity := &state.Nodes[i]
+		value := entity.Text
+		if ref := entity.attr("var_ref"); ref != "" {
+			values, _ := e.variable(ref)
+			value = strings.Join(values, "|")
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s", entity.XMLName.Local, valueOr(entity.attr("operation"), "equals"), value))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (e *ovalEval) matchState(item ovalItem, state *ovalNode, d *ovalTestDetail) ovalResult {
+	results := []ovalResult{}
+	for i := range state.Nodes {
+		entity := &state.Nodes[i]
+		actual, ok := item.fields[entity.XMLName.Local]
+		if !ok || len(actual) == 0 {
+			results = append(results, ovalFalse)
+			continue
+		}
+		expected, err := e.entityValues(entity)
+		if err != nil {
+			d.err = err
+			results = append(results, ovalError)
+			continue
+		}
+		op, datatype := entity.attr("operation"), entity.attr("datatype")
+		// entity_check spans the item's values, var_check the variable's.
+		perActual := []ovalResult{}
+		for _, a := range actual {
+			perExpected := []ovalResult{}
+			for _, x := range expected {
+				match, err := ovalCompare(a, x, op, datatype)
+				if err != nil {
+					d.err = err
+					perExpected = append(perExpected, ovalError)
+					continue
+				}
+				perExpected = append(perExpected, ovalBool(match))
+			}
+			perActual = append(perActual, ovalCheck(entity.attr("var_check"), perExpected))
+		}
+		results = append(results, ovalCheck(entity.attr("entity_check"), perActual))
+	}
+	return combineOVAL(valueOr(state.attr("operator"), "AND"), results)
+}
+
+func ovalCheck(check string, results []ovalResult) ovalResult {
+	switch check {
+	case "at least one":
+		return combineOVAL("OR", results)
+	case "only one":
+		return combineOVAL("ONE", results)
+	case "none satisfy":
+		negated := make([]ovalResult, len(results))
+		for i, r := range results {
+			negated[i] = r.negate()
+		}
+		return combineOVAL("AND", negated)
+	}
+	return combineOVAL("AND", results)
+}
+
+func (e *ovalEval) entityValues(entity *ovalNode) ([]string, error) {
+	if ref := entity.attr("var_ref"); ref != "" {
+		return e.variable(ref)
+	}
+	return []string{entity.Text}, nil
+}
+
+// variable resolves constant_variable only; local and external variables
+// need a full OVAL interpreter or values supplied at run time.
+func (e *ovalEval) variable(id string) ([]string, error) {
+	v := e.c.variables[id]
+	if v == nil {
+		return nil, fmt.Errorf("未定义的变量 %s", id)
+	}
+	if v.XMLName.Local != "constant_variable" {
+		return nil, fmt.Errorf("不支持的变量类型 %s（%s）", v.XMLName.Local, id)
+	}
+	values := []string{}
+	for i := range v.Nodes {
+		if v.Nodes[i].XMLName.Local == "value" {
+			values = append(values, v.Nodes[i].Text)
+		}
+	}
+	return values, nil
+}
+
+// objectValues returns the values of an object entity, which must use the
+// equals operation unless allowPattern is set.
+func (e *ovalEval) objectValues(object *ovalNode, name string, allowPattern bool) ([]string, string, error) {
+	entity := object.child(name)
+	if entity == nil {
+		return nil, "", nil
+	}
+	op := valueOr(entity.attr("operation"), "equals")
+	if op != "equals" && !(allowPattern && op == "pattern match") {
+		return nil, "", fmt.Errorf("%s 不支持 %s 操作", name, op)
+	}
+	values, err := e.entityValues(entity)
+	return values, op, err
+}
+
+type ovalCollector func(e *ovalEval, object *ovalNode) ([]ovalItem, *Evidence, error)
+
+var ovalCollectors = map[string]ovalCollector{
+	"textfilecontent54": collectTextFileContent,
+	"sysctl":            collectSysctl,
+	"rpminfo":           collectRPMInfo,
+	"dpkginfo":          collectDpkgInfo,
+	"family":            collectFamily,
+	"variable":          collectVariable,
+}
+
+func collectTextFileContent(e *ovalEval, object *ovalNode) ([]ovalItem, *Evidence, error) {
+	if object.child("set") != nil || object.child("filter") != nil {
+		return nil, nil, errors.New("不支持 set/filter 对象")
+	}
+	files, _, err := e.objectValues(object, "filepath", false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if object.child("filepath") == nil {
+		dirs, _, err := e.objectValues(object, "path", false)
+		if err != nil {
+			return nil, nil, err
+		}
+		names, op, err := e.objectValues(object, "filename", true)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, dir := range dirs {
+			for _, name := range names {
+				if op != "pattern match" {
+					files = append(files, path.Join(dir, name))
+					continue
+				}
+				re, err := regexp.Compile(name)
+				if err != nil {
+					return nil, nil, err
+				}
+				entries, _ := e.rc.Sys.ReadDir(dir)
+				for _, entry := range entries {
+					if !entry.IsDir() && re.MatchString(entry.Name()) {
+						files = append(files, path.Join(dir, entry.Name()))
+					}
+				}
+			}
+		}
+	}
+	patterns, _, err := e.objectValues(object, "pattern", true)
+	if err != nil {
+		return nil, nil, err
+	}
+	flags := "(?m)"
+	if b := object.child("behaviors"); b != nil {
+		if b.attr("multiline") == "false" {
+			flags = ""
+		}
+		if b.attr("singleline") == "true" {
+			flags += "(?s)"
+		}
+	}
+	instance := object.child("instance")
+	items := []ovalItem{}
+	var source *Evidence
+	for _, file := range files {
+		data, err := e.rc.Sys.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		if source == nil {
+			source = &Evidence{File: file}
+		}
+		content := string(data)
+		for _, pattern := range patterns {
+			re, err := regexp.Compile(flags + pattern)
+			if err != nil {
+				return nil, source, err
+			}
+			for n, m := range re.FindAllStringSubmatchIndex(content, -1) {
+				if instance != nil {
+					want, err := e.entityValues(instance)
+					if err != nil {
+						return nil, source, err
+					}
+					ok := false
+					for _, w := range want {
+						if match, err := ovalCompare(strconv.Itoa(n+1), w, instance.attr("operation"), "int"); err == nil && match {
+							ok = true
+						}
+					}
+					if !ok {
+						continue
+					}
+				}
+				text := content[m[0]:m[1]]
+				subs := []string{}
+				for g := 2; g+1 < len(m); g += 2 {
+					if m[g] >= 0 {
+						subs = append(subs, content[m[g]:m[g+1]])
+					}
+				}
+				line := strings.Count(content[:m[0]], "\n") + 1
+				items = append(items, ovalItem{
+					fields: map[string][]string{
+						"filepath": {file}, "path": {path.Dir(file)}, "filename": {path.Base(file)},
+						"pattern": {pattern}, "instance": {strconv.Itoa(n + 1)}, "text": {text}, "subexpression": subs,
+					},
+					observed: fmt.Sprintf("%s:%d %s", file, line, strings.TrimSpace(text)),
+				})
+				if len(items) == 1 {
+					source = &Evidence{File: file, Line: line}
+				}
+			}
+		}
+	}
+	return items, source, nil
+}
+
+func collectSysctl(e *ovalEval, object *ovalNode) ([]ovalItem, *Evidence, error) {
+	if !e.rc.Sys.Live() {
+		return nil, nil, errors.New("离线扫描无法读取内核参数")
+	}
+	names, _, err := e.objectValues(object, "name", false)
+	if err != nil {
+		return nil, nil, err
+	}
+	items := []ovalItem{}
+	var source *Evidence
+	for _, name := range names {
+		p := "/proc/sys/" + strings.ReplaceAll(name, ".", "/")
+		source = &Evidence{File: p}
+		data, err := e.rc.Sys.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		value := strings.TrimSpace(string(data))
+		items = append(items, ovalItem{
+			fields:   map[string][]string{"name": {name}, "value": {value}},
+			observed: name + "=" + value,
+		})
+	}
+	return items, source, nil
+}
+
+type ovalPackage struct {
+	name, epoch, version, release, arch string
+}
+
+func (p ovalPackage) item() ovalItem {
+	evr := p.epoch + ":" + p.version
+	if p.release != "" {
+		evr += "-" + p.release
+	}
+	return ovalItem{
+		fields: map[string][]string{
+			"name": {p.name}, "arch": {p.arch}, "epoch": {p.epoch},
+			"version": {p.version}, "release": {p.release}, "evr": {evr},
+		},
+		observed: p.name + " " + evr,
+	}
+}
+
+func collectRPMInfo(e *ovalEval, object *ovalNode) ([]ovalItem, *Evidence, error) {
+	names, _, err := e.objectValues(object, "name", false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !e.rc.commandExists("rpm") {
+		return nil, nil, errors.New("未找到 rpm 命令")
+	}
+	items := []ovalItem{}
+	var source *Evidence
+	for _, name := range names {
+		args := []string{"-q", "--qf", `%{NAME}\t%{EPOCHNUM}\t%{VERSION}\t%{RELEASE}\t%{ARCH}\n`, name}
+		source = &Evidence{Command: "rpm -q " + name}
+		out, code := e.rc.runCommand("rpm", args...)
+		if code != 0 {
+			continue
+		}
+		for _, line := range splitFileLines(out) {
+			f := strings.Split(line, "\t")
+			if len(f) == 5 {
+				items = append(items, ovalPackage{name: f[0], epoch: f[1], version: f[2], release: f[3], arch: f[4]}.item())
+			}
+		}
+	}
+	return items, source, nil
+}
+
+const dpkgStatusPath = "/var/lib/dpkg/status"
+
+// collectDpkgInfo reads the dpkg database directly so it also works on an
+// offline root filesystem.
+func collectDpkgInfo(e *ovalEval, object *ovalNode) ([]ovalItem, *Evidence, error) {
+	names, op, err := e.objectValues(object, "name", true)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := e.rc.Sys.ReadFile(dpkgStatusPath)
+	if err != nil {
+		return nil, nil, errors.New("无法读取 " + dpkgStatusPath)
+	}
+	source := &Evidence{File: dpkgStatusPath}
+	items := []ovalItem{}
+	for _, stanza := range strings.Split(string(data), "\n\n") {
+		fields := map[string]string{}
+		for _, line := range strings.Split(stanza, "\n") {
+			if key, value, ok := strings.Cut(line, ":"); ok && !strings.HasPrefix(line, " ") {
+				fields[key] = strings.TrimSpace(value)
+			}
+		}
+		if !strings.HasSuffix(fields["Status"], " installed") {
+			continue
+		}
+		matched := false
+		for _, name := range names {
+			match, err := ovalCompare(fields["Package"], name, op, "string")
+			if err != nil {
+				return nil, source, err
+			}
+			matched = matched || match
+		}
+		if !matched {
+			continue
+		}
+		epoch, upstream, revision := splitDebVersion(fields["Version"])
+		items = append(items, ovalPackage{
+			name: fields["Package"], epoch: strconv.Itoa(epoch), version: upstream, release: revision, arch: fields["Architecture"],
+		}.item())
+	}
+	return items, source, nil
+}
+
+func collectFamily(e *ovalEval, object *ovalNode) ([]ovalItem, *Evidence, error) {
+	return []ovalItem{{fields: map[string][]string{"family": {"unix"}}, observed: "unix"}}, nil, nil
+}
+
+func collectVariable(e *ovalEval, object *ovalNode) ([]ovalItem, *Evidence, error) {
+	ref := object.childText("var_ref")
+	values, err := e.variable(ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	return []ovalItem{{
+		fields:   map[string][]string{"var_ref": {ref}, "value": values},
+		observed: ref + "=" + strings.Join(values, "|"),
+	}}, nil, nil
+}
+
+func ovalCompare(actual, expected, op, datatype string) (bool, error) {
+	switch op {
+	case "pattern match":
+		re, err := regexp.Compile(expected)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(actual), nil
+	case "case insensitive equals":
+		return strings.EqualFold(actual, expected), nil
+	case "case insensitive not equal":
+		return !strings.EqualFold(actual, expected), nil
+	}
+	cmp, err := ovalOrder(actual, expected, datatype)
+	if err != nil {
+		return false, err
+	}
+	switch op {
+	case "", "equals":
+		return cmp == 0, nil
+	case "not equal":
+		return cmp != 0, nil
+	case "greater than":
+		return cmp > 0, nil
+	case "less than":
+		return cmp < 0, nil
+	case "greater than or equal":
+		return cmp >= 0, nil
+	case "less than or equal":
+		return cmp <= 0, nil
+	}
+	return false, fmt.Errorf("不支持的比较操作 %s", op)
+}
+
+func ovalOrder(a, b, datatype string) (int, error) {
+	switch datatype {
+	case "", "string":
+		return strings.Compare(a, b), nil
+	case "int":
+		x, err1 := strconv.ParseInt(strings.TrimSpace(a), 10, 64)
+		y, err2 := strconv.ParseInt(strings.TrimSpace(b), 10, 64)
+		if err1 != nil || err2 != nil {
+			return 0, fmt.Errorf("无法按整数比较 %q 与 %q", a, b)
+		}
+		return compareInt64(x, y), nil
+	case "boolean":
+		truth := func(s string) int {
+			if s == "true" || s == "1" {
+				return 1
+			}
+			return 0
+		}
+		return truth(a) - truth(b), nil
+	case "version":
+		return compareDottedVersion(a, b), nil
+	case "evr_string":
+		return compareRPMEVR(a, b), nil
+	case "debian_evr_string":
+		return compareDebVersion(a, b), nil
+	}
+	return 0, fmt.Errorf("不支持的数据类型 %s", datatype)
+}
+
+func compareInt64(x, y int64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func compareDottedVersion(a, b string) int {
+	split := func(s string) []string {
+		return strings.FieldsFunc(s, func(r rune) bool { return r == '.' || r == '-' || r == '_' })
+	}
+	pa, pb := split(a), split(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y string
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		nx, errX := strconv.ParseInt(valueOr(x, "0"), 10, 64)
+		ny, errY := strconv.ParseInt(valueOr(y, "0"), 10, 64)
+		if errX == nil && errY == nil {
+			if c := compareInt64(nx, ny); c != 0 {
+				return c
+			}
+			continue
+		}
+		if c := strings.Compare(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isAlpha(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+
+// compareRPMEVR compares epoch:version-release strings like rpm does.
+func compareRPMEVR(a, b string) int {
+	split := func(s string) (int64, string, string) {
+		var epoch int64
+		if e, rest, ok := strings.Cut(s, ":"); ok {
+			epoch, _ = strconv.ParseInt(e, 10, 64)
+			s = rest
+		}
+		if i := strings.LastIndex(s, "-"); i >= 0 {
+			return epoch, s[:i], s[i+1:]
+		}
+		return epoch, s, ""
+	}
+	ea, va, ra := split(a)
+	eb, vb, rb := split(b)
+	if c := compareInt64(ea, eb); c != 0 {
+		return c
+	}
+	if c := rpmVerCmp(va, vb); c != 0 {
+		return c
+	}
+	return rpmVerCmp(ra, rb)
+}
+
+// rpmVerCmp is rpm's rpmvercmp: alternating numeric and alphabetic
+// segments, numbers compared numerically and newer than letters, and "~"
+// sorting before everything.
+func rpmVerCmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+	separator := func(c byte) bool { return !isDigit(c) && !isAlpha(c) && c != '~' }
+	for a != "" || b != "" {
+		for a != "" && separator(a[0]) {
+			a = a[1:]
+		}
+		for b != "" && separator(b[0]) {
+			b = b[1:]
+		}
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if a == "" || b == "" {
+			break
+		}
+		class := isAlpha
+		if isDigit(a[0]) {
+			class = isDigit
+		}
+		span := func(s string) (string, string) {
+			i := 0
+			for i < len(s) && class(s[i]) {
+				i++
+			}
+			return s[:i], s[i:]
+		}
+		var sa, sb string
+		sa, a = span(a)
+		sb, b = span(b)
+		if sb == "" {
+			if isDigit(sa[0]) {
+				return 1
+			}
+			return -1
+		}
+		if isDigit(sa[0]) {
+			sa, sb = strings.TrimLeft(sa, "0"), strings.TrimLeft(sb, "0")
+			if len(sa) != len(sb) {
+				return compareInt64(int64(len(sa)), int64(len(sb)))
+			}
+		}
+		if c := strings.Compare(sa, sb); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	}
+	return 1
+}
+
+func splitDebVersion(v string) (int, string, string) {
+	epoch := 0
+	if e, rest, ok := strings.Cut(v, ":"); ok {
+		epoch, _ = strconv.Atoi(e)
+		v = rest
+	}
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		return epoch, v[:i], v[i+1:]
+	}
+	return epoch, v, ""
+}
+
+// compareDebVersion follows dpkg's version ordering.
+func compareDebVersion(a, b string) int {
+	ea, ua, ra := splitDebVersion(a)
+	eb, ub, rb := splitDebVersion(b)
+	if c := compareInt64(int64(ea), int64(eb)); c != 0 {
+		return c
+	}
+	if c := debVerRevCmp(ua, ub); c != 0 {
+		return c
+	}
+	return debVerRevCmp(ra, rb)
+}
+
+func debOrder(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+	c := s[i]
+	switch {
+	case isDigit(c):
+		return 0
+	case isAlpha(c):
+		return int(c)
+	case c == '~':
+		return -1
+	}
+	return int(c) + 256
+}
+
+func debVerRevCmp(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			ac, bc := debOrder(a, i), debOrder(b, j)
+			if ac != bc {
+				return compareInt64(int64(ac), int64(bc))
+			}
+			i++
+			j++
+		}
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+		first := 0
+		for i < len(a) && isDigit(a[i]) && j < len(b) && isDigit(b[j]) {
+			if first == 0 {
+				first = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if first != 0 {
+			return compareInt64(int64(first), 0)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,237 @@
+package main
+
+import "testing"
+
+func TestCombineOVAL(t *testing.T) {
+	const (
+		T  = ovalTrue
+		F  = ovalFalse
+		E  = ovalError
+		U  = ovalUnknown
+		NE = ovalNotEvaluated
+		NA = ovalNotApplicable
+	)
+	tests := []struct {
+		operator string
+		results  []ovalResult
+		negate   bool
+		want     ovalResult
+	}{
+		{"AND", []ovalResult{T, T}, false, T},
+		{"AND", []ovalResult{T, F}, false, F},
+		{"AND", []ovalResult{F, E}, false, F},
+		{"AND", []ovalResult{T, E}, false, E},
+		{"AND", []ovalResult{T, U, NE}, false, U},
+		{"AND", []ovalResult{T, NA}, false, T},
+		{"AND", []ovalResult{NA, NA}, false, NA},
+		{"AND", nil, false, NA},
+		{"AND", []ovalResult{T, T}, true, F},
+		{"AND", []ovalResult{T, F}, true, T},
+		{"AND", []ovalResult{T, E}, true, E},
+		{"", []ovalResult{T, F}, false, F},
+
+		{"OR", []ovalResult{F, T}, false, T},
+		{"OR", []ovalResult{F, F}, false, F},
+		{"OR", []ovalResult{T, E}, false, T},
+		{"OR", []ovalResult{F, E}, false, E},
+		{"OR", []ovalResult{F, NE}, false, NE},
+		{"OR", []ovalResult{F, NA}, false, F},
+		{"OR", []ovalResult{F, F}, true, T},
+		{"OR", []ovalResult{F, T}, true, F},
+		{"OR", []ovalResult{F, U}, true, U},
+
+		{"ONE", []ovalResult{T, F, F}, false, T},
+		{"ONE", []ovalResult{T, T, F}, false, F},
+		{"ONE", []ovalResult{F, F}, false, F},
+		{"ONE", []ovalResult{T, T, E}, false, F},
+		{"ONE", []ovalResult{T, F, E}, false, E},
+		{"ONE", []ovalResult{T, NA}, false, T},
+		{"ONE", []ovalResult{T, F}, true, F},
+		{"ONE", []ovalResult{T, T}, true, T},
+
+		{"XOR", []ovalResult{T, F}, false, T},
+		{"XOR", []ovalResult{T, T}, false, F},
+		{"XOR", []ovalResult{T, T, T}, false, T},
+		{"XOR", []ovalResult{F, F}, false, F},
+		{"XOR", []ovalResult{T, E}, false, E},
+		{"XOR", []ovalResult{T, NA}, false, T},
+		{"XOR", []ovalResult{T, F}, true, F},
+		{"XOR", []ovalResult{T, T}, true, T},
+	}
+	for _, tt := range tests {
+		got := combineOVAL(tt.operator, tt.results)
+		if tt.negate {
+			got = got.negate()
+		}
+		if got != tt.want {
+			t.Errorf("combineOVAL(%q, %v) negate=%v = %v, want %v", tt.operator, tt.results, tt.negate, got, tt.want)
+		}
+	}
+}
+
+func TestOVALNegate(t *testing.T) {
+	tests := map[ovalResult]ovalResult{
+		ovalTrue:          ovalFalse,
+		ovalFalse:         ovalTrue,
+		ovalError:         ovalError,
+		ovalUnknown:       ovalUnknown,
+		ovalNotEvaluated:  ovalNotEvaluated,
+		ovalNotApplicable: ovalNotApplicable,
+	}
+	for in, want := range tests {
+		if got := in.negate(); got != want {
+			t.Errorf("%v.negate() = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestOVALCheck(t *testing.T) {
+	tests := []struct {
+		check   string
+		results []ovalResult
+		want    ovalResult
+	}{
+		{"all", []ovalResult{ovalTrue, ovalTrue}, ovalTrue},
+		{"all", []ovalResult{ovalTrue, ovalFalse}, ovalFalse},
+		{"at least one", []ovalResult{ovalFalse, ovalTrue}, ovalTrue},
+		{"only one", []ovalResult{ovalTrue, ovalTrue}, ovalFalse},
+		{"none satisfy", []ovalResult{ovalFalse, ovalFalse}, ovalTrue},
+		{"none satisfy", []ovalResult{ovalFalse, ovalTrue}, ovalFalse},
+		{"none satisfy", []ovalResult{ovalFalse, ovalError}, ovalError},
+	}
+	for _, tt := range tests {
+		if got := ovalCheck(tt.check, tt.results); got != tt.want {
+			t.Errorf("ovalCheck(%q, %v) = %v, want %v", tt.check, tt.results, got, tt.want)
+		}
+	}
+}
+
+// versionCases are checked in both directions: compare(b, a) must be -want.
+type versionCase struct {
+	a, b string
+	want int
+}
+
+func checkVersionOrder(t *testing.T, name string, compare func(a, b string) int, tests []versionCase) {
+	t.Helper()
+	for _, tt := range tests {
+		if got := compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s(%q, %q) = %d, want %d", name, tt.a, tt.b, got, tt.want)
+		}
+		if got := compare(tt.b, tt.a); got != -tt.want {
+			t.Errorf("%s(%q, %q) = %d, want %d", name, tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestRPMVerCmp(t *testing.T) {
+	checkVersionOrder(t, "rpmVerCmp", rpmVerCmp, []versionCase{
+		{"1.0", "1.0", 0},
+		{"1.0", "1.1", -1},
+		{"1.10", "1.9", 1},
+		{"001", "1", 0},
+		{"1.0", "1_0", 0},
+		{"1.0a", "1.0", 1},
+		{"1.a", "1.1", -1},
+		{"a", "b", -1},
+		{"2.0.1", "2.0.1a", -1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0~rc1", "1.0~rc2", -1},
+		{"1.0~~", "1.0~", -1},
+		{"5.3.28", "5.3.28.1", -1},
+	})
+}
+
+func TestCompareRPMEVR(t *testing.T) {
+	checkVersionOrder(t, "compareRPMEVR", compareRPMEVR, []versionCase{
+		{"1.0-1", "1.0-1", 0},
+		{"0:1.0-1", "1.0-1", 0},
+		{"1.0-1", "1.0-2", -1},
+		{"1.0-1.el8", "1.0-1.el9", -1},
+		{"1:1.0-1", "2.0-1", 1},
+		{"2.17-326.el7", "2.17-55.el7", 1},
+		{"8.0p1-19.ky10", "8.2p1-9.ky10", -1},
+	})
+}
+
+func TestCompareDebVersion(t *testing.T) {
+	checkVersionOrder(t, "compareDebVersion", compareDebVersion, []versionCase{
+		{"1.0", "1.0", 0},
+		{"1.0", "1.00", 0},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0~~", "1.0~", -1},
+		{"1.0", "1.0+b1", -1},
+		{"1.0a", "1.0+", -1},
+		{"1.0", "1.0a", -1},
+		{"1:0.9", "2.0", 1},
+		{"1.0-1", "1.0-1ubuntu1", -1},
+		{"1.0-1", "1.0-2", -1},
+		{"2.30-0ubuntu1", "2.4-1", 1},
+		{"1:8.2p1-4", "1:8.2p1-4+deb11u1", -1},
+	})
+}
+
+func TestSplitDebVersion(t *testing.T) {
+	tests := []struct {
+		in       string
+		epoch    int
+		upstream string
+		revision string
+	}{
+		{"1.0", 0, "1.0", ""},
+		{"1.0-3", 0, "1.0", "3"},
+		{"2:1.0-3", 2, "1.0", "3"},
+		{"1:2.3-4-5", 1, "2.3-4", "5"},
+	}
+	for _, tt := range tests {
+		epoch, upstream, revision := splitDebVersion(tt.in)
+		if epoch != tt.epoch || upstream != tt.upstream || revision != tt.revision {
+			t.Errorf("splitDebVersion(%q) = %d, %q, %q, want %d, %q, %q", tt.in, epoch, upstream, revision, tt.epoch, tt.upstream, tt.revision)
+		}
+	}
+}
+
+func TestCompareDottedVersion(t *testing.T) {
+	checkVersionOrder(t, "compareDottedVersion", compareDottedVersion, []versionCase{
+		{"5.10.0", "5.9", 1},
+		{"1.0", "1", 0},
+		{"4.19.90-23", "4.19.90-24", -1},
+		{"1.0.a", "1.0.b", -1},
+	})
+}
+
+func TestOVALCompare(t *testing.T) {
+	tests := []struct {
+		actual, expected, op, datatype string
+		want                           bool
+	}{
+		{"10", "9", "greater than", "int", true},
+		{"10", "9", "greater than", "string", false},
+		{" 5 ", "5", "equals", "int", true},
+		{"5.10", "5.9", "greater than or equal", "version", true},
+		{"1.0-1.el8", "1.0-2.el8", "less than", "evr_string", true},
+		{"1.0~rc1", "1.0", "less than", "debian_evr_string", true},
+		{"true", "1", "equals", "boolean", true},
+		{"abc", "abd", "not equal", "", true},
+		{"Yes", "yes", "case insensitive equals", "", true},
+		{"Yes", "no", "case insensitive not equal", "", true},
+		{"PermitRootLogin no", `^PermitRootLogin\s+no$`, "pattern match", "", true},
+		{"3", "3", "less than or equal", "int", true},
+	}
+	for _, tt := range tests {
+		got, err := ovalCompare(tt.actual, tt.expected, tt.op, tt.datatype)
+		if err != nil || got != tt.want {
+			t.Errorf("ovalCompare(%q, %q, %q, %q) = %v, %v, want %v", tt.actual, tt.expected, tt.op, tt.datatype, got, err, tt.want)
+		}
+	}
+	for _, tt := range []struct{ actual, expected, op, datatype string }{
+		{"x", "1", "equals", "int"},
+		{"1", "1", "equals", "float"},
+		{"1", "1", "bitwise and", "int"},
+		{"a", "(", "pattern match", ""},
+	} {
+		if _, err := ovalCompare(tt.actual, tt.expected, tt.op, tt.datatype); err == nil {
+			t.Errorf("ovalCompare(%q, %q, %q, %q) succeeded, want error", tt.actual, tt.expected, tt.op, tt.datatype)
+		}
+	}
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testPolicy = PasswordProfile{MinLen: 10, MinClass: 4, Remember: 5, Deny: 5, UnlockTime: 600}
+
+const (
+	testPreauth  = "auth required pam_faillock.so preauth silent deny=5 unlock_time=600"
+	testAuthfail = "auth [default=die] pam_faillock.so authfail deny=5 unlock_time=600"
+	testAccount  = "account required pam_faillock.so"
+	testPwqual   = "password requisite pam_pwquality.so retry=3 enforce_for_root minlen=10 minclass=4"
+)
+
+// Stock stacks as shipped by Debian/UOS (common-auth, common-password) and
+// RHEL/Kylin (system-auth).
+var (
+	debianCommonAuth = []string{
+		`# here are the per-package modules (the "Primary" block)`,
+		"auth\t[success=1 default=ignore]\tpam_unix.so nullok",
+		"# here's the fallback if no module succeeds",
+		"auth\trequisite\t\t\tpam_deny.so",
+		"# prime the stack with a positive return value if there isn't one already",
+		"auth\trequired\t\t\tpam_permit.so",
+		`# and here are more per-package modules (the "Additional" block)`,
+		"auth\toptional\t\t\tpam_cap.so",
+	}
+	debianCommonPassword = []string{
+		"password\t[success=1 default=ignore]\tpam_unix.so obscure yescrypt",
+		"password\trequisite\t\t\tpam_deny.so",
+		"password\trequired\t\t\tpam_permit.so",
+	}
+	rhelSystemAuth = []string{
+		"auth        required      pam_env.so",
+		"auth        required      pam_faildelay.so delay=2000000",
+		"auth        sufficient    pam_unix.so nullok try_first_pass",
+		"auth        requisite     pam_succeed_if.so uid >= 1000 quiet_success",
+		"auth        required      pam_deny.so",
+		"",
+		"account     required      pam_unix.so",
+		"",
+		"password    requisite     pam_pwquality.so try_first_pass local_users_only retry=3 authtok_type=",
+		"password    sufficient    pam_unix.so sha512 shadow nullok try_first_pass use_authtok",
+		"password    required      pam_deny.so",
+	}
+)
+
+func applyPamLines(lines []string, pls []pamLine) ([]string, error) {
+	lines = append([]string(nil), lines...)
+	var err error
+	for _, pl := range pls {
+		if lines, err = ensurePamLine(lines, pl); err != nil {
+			return nil, err
+		}
+	}
+	return lines, nil
+}
+
+func TestEnsurePamLineFaillock(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "debian common-auth",
+			in:   debianCommonAuth,
+			want: []string{
+				debianCommonAuth[0],
+				testPreauth,
+				"auth\t[success=2 default=ignore]\tpam_unix.so nullok",
+				testAuthfail,
+				debianCommonAuth[2], debianCommonAuth[3], debianCommonAuth[4],
+				debianCommonAuth[5], debianCommonAuth[6], debianCommonAuth[7],
+				testAccount,
+			},
+		},
+		{
+			name: "rhel system-auth",
+			in:   rhelSystemAuth,
+			want: []string{
+				rhelSystemAuth[0], rhelSystemAuth[1],
+				testPreauth,
+				rhelSystemAuth[2],
+				testAuthfail,
+				rhelSystemAuth[3], rhelSystemAuth[4], rhelSystemAuth[5], rhelSystemAuth[6],
+				rhelSystemAuth[7], rhelSystemAuth[8], rhelSystemAuth[9], rhelSystemAuth[10],
+				testAccount,
+			},
+		},
+		{
+			name: "debian with sss",
+			in: []string{
+				"auth [success=2 default=ignore] pam_unix.so nullok",
+				"auth [success=1 default=ignore] pam_sss.so use_first_pass",
+				"auth requisite pam_deny.so",
+				"auth required pam_permit.so",
+			},
+			want: []string{
+				testPreauth,
+				"auth [success=3 default=ignore] pam_unix.so nullok",
+				testAuthfail,
+				"auth [success=1 default=ignore] pam_sss.so use_first_pass",
+				"auth requisite pam_deny.so",
+				"auth required pam_permit.so",
+				testAccount,
+			},
+		},
+		{
+			name: "existing lines replaced in place, comments ignored",
+			in: []string{
+				"#auth required pam_faillock.so preauth silent deny=3",
+				"auth required pam_faillock.so preauth silent deny=3 unlock_time=60",
+				"auth sufficient pam_unix.so",
+				"auth [default=die] pam_faillock.so authfail deny=3",
+				"auth required pam_deny.so",
+				"account required pam_faillock.so",
+			},
+			want: []string{
+				"#auth required pam_faillock.so preauth silent deny=3",
+				testPreauth,
+				"auth sufficient pam_unix.so",
+				testAuthfail,
+				"auth required pam_deny.so",
+				testAccount,
+			},
+		},
+		{
+			name:    "no pam_unix",
+			in:      []string{"auth required pam_env.so", "#auth sufficient pam_unix.so", "auth required pam_deny.so"},
+			wantErr: "未找到 auth pam_unix.so 行",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyPamLines(tt.in, faillockPamLines(testPolicy))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			if err := checkAuthStack(got); err != nil {
+				t.Errorf("checkAuthStack: %v", err)
+			}
+		})
+	}
+}
+
+func TestEnsurePamLineTally2(t *testing.T) {
+	got, err := applyPamLines(debianCommonAuth[1:4], tally2PamLines(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"auth required pam_tally2.so deny=5 unlock_time=600",
+		"auth\t[success=1 default=ignore]\tpam_unix.so nullok",
+		debianCommonAuth[2], debianCommonAuth[3],
+		"account required pam_tally2.so",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestInsertPamLine(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []string
+		at   int
+		line string
+		want []string
+	}{
+		{
+			name: "jump over the insertion point is widened",
+			in:   []string{"auth [success=1 default=ignore] pam_a.so", "auth requisite pam_deny.so"},
+			at:   1,
+			line: "auth required pam_x.so",
+			want: []string{"auth [success=2 default=ignore] pam_a.so", "auth required pam_x.so", "auth requisite pam_deny.so"},
+		},
+		{
+			name: "jump landing on the insertion point is kept",
+			in:   []string{"auth [success=1 default=ignore] pam_a.so", "auth required pam_b.so", "auth required pam_c.so"},
+			at:   2,
+			line: "auth required pam_x.so",
+			want: []string{"auth [success=1 default=ignore] pam_a.so", "auth required pam_b.so", "auth required pam_x.so", "auth required pam_c.so"},
+		},
+		{
+			name: "later modules are not touched",
+			in:   []string{"auth required pam_a.so", "auth [success=1 default=ignore] pam_b.so", "auth required pam_c.so"},
+			at:   1,
+			line: "auth required pam_x.so",
+			want: []string{"auth required pam_a.so", "auth required pam_x.so", "auth [success=1 default=ignore] pam_b.so", "auth required pam_c.so"},
+		},
+		{
+			name: "every numeric action is widened",
+			in:   []string{"account [success=1 new_authtok_reqd=done default=ignore ignore=2] pam_unix.so", "account requisite pam_deny.so"},
+			at:   1,
+			line: "account required pam_x.so",
+			want: []string{"account [success=2 new_authtok_reqd=done default=ignore ignore=3] pam_unix.so", "account required pam_x.so", "account requisite pam_deny.so"},
+		},
+		{
+			name: "other types and comments do not count",
+			in: []string{
+				"password [success=1 default=ignore] pam_unix.so",
+				"auth [success=1 default=ignore] pam_unix.so",
+				"# comment",
+				"",
+				"auth requisite pam_deny.so",
+			},
+			at:   4,
+			line: "auth required pam_x.so",
+			want: []string{
+				"password [success=1 default=ignore] pam_unix.so",
+				"auth [success=2 default=ignore] pam_unix.so",
+				"# comment",
+				"",
+				"auth required pam_x.so",
+				"auth requisite pam_deny.so",
+			},
+		},
+		{
+			name: "dash prefixed type counts",
+			in:   []string{"-auth [success=1 default=ignore] pam_a.so", "auth requisite pam_deny.so"},
+			at:   1,
+			line: "auth required pam_x.so",
+			want: []string{"-auth [success=2 default=ignore] pam_a.so", "auth required pam_x.so", "auth requisite pam_deny.so"},
+		},
+		{
+			name: "append after the last module",
+			in:   []string{"auth [success=1 default=ignore] pam_a.so", "auth required pam_b.so"},
+			at:   2,
+			line: "auth required pam_x.so",
+			want: []string{"auth [success=1 default=ignore] pam_a.so", "auth required pam_b.so", "auth required pam_x.so"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := insertPamLine(append([]string(nil), tt.in...), tt.at, tt.line)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestCheckAuthStack(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []string
+		wantErr string
+	}{
+		{"stock debian", debianCommonAuth, ""},
+		{"stock rhel", rhelSystemAuth, ""},
+		{"success=done", []string{"auth [success=done default=ignore] pam_unix.so", "auth requisite pam_deny.so"}, ""},
+		{
+			name:    "authfail without widened jump",
+			in:      []string{"auth [success=1 default=ignore] pam_unix.so", testAuthfail, "auth requisite pam_deny.so", "auth required pam_permit.so"},
+			wantErr: "认证成功后将执行 pam_deny.so",
+		},
+		{
+			name:    "authfail after required pam_unix",
+			in:      []string{"auth required pam_unix.so", testAuthfail},
+			wantErr: "认证成功后将执行 pam_faillock.so",
+		},
+		{
+			name:    "appended authfail reached by the jump",
+			in:      []string{"auth [success=1 default=ignore] pam_unix.so", "auth requisite pam_deny.so", testAuthfail},
+			wantErr: "认证成功后将执行 pam_faillock.so",
+		},
+		{
+			name:    "deny before pam_unix",
+			in:      []string{testAuthfail, "auth sufficient pam_unix.so"},
+			wantErr: "位于 pam_unix.so 之前",
+		},
+		{
+			name:    "no pam_unix",
+			in:      []string{"auth required pam_env.so", "# auth sufficient pam_unix.so", "auth optional pam_cap.so"},
+			wantErr: "缺少 pam_unix.so",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkAuthStack(tt.in)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkAuthStack: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnsurePasswordPamLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       []string
+		remember int
+		want     []string
+		wantErr  string
+	}{
+		{
+			name:     "debian common-password",
+			in:       debianCommonPassword,
+			remember: 5,
+			want: []string{
+				testPwqual,
+				"password\t[success=1 default=ignore]\tpam_unix.so obscure yescrypt remember=5",
+				debianCommonPassword[1], debianCommonPassword[2],
+			},
+		},
+		{
+			name: "debian with pwquality and remember",
+			in: []string{
+				"password\trequisite\t\t\tpam_pwquality.so retry=3",
+				"password\t[success=1 default=ignore]\tpam_unix.so obscure use_authtok try_first_pass yescrypt remember=3",
+				"password\trequisite\t\t\tpam_deny.so",
+			},
+			remember: 5,
+			want: []string{
+				testPwqual,
+				"password\t[success=1 default=ignore]\tpam_unix.so obscure use_authtok try_first_pass yescrypt remember=5",
+				"password\trequisite\t\t\tpam_deny.so",
+			},
+		},
+		{
+			name:     "rhel system-auth",
+			in:       rhelSystemAuth,
+			remember: 5,
+			want: append(append(append([]string(nil), rhelSystemAuth[:8]...), testPwqual,
+				"password    sufficient    pam_unix.so sha512 shadow nullok try_first_pass use_authtok remember=5"),
+				rhelSystemAuth[10]),
+		},
+		{
+			name:     "remember 0 leaves pam_unix alone",
+			in:       debianCommonPassword,
+			remember: 0,
+			want:     append([]string{testPwqual}, debianCommonPassword...),
+		},
+		{
+			name:     "missing pam_unix goes before pam_deny",
+			in:       []string{"#password sufficient pam_unix.so", "password requisite pam_cracklib.so", "password required pam_deny.so"},
+			remember: 5,
+			want: []string{
+				"#password sufficient pam_unix.so",
+				testPwqual,
+				"password sufficient pam_unix.so sha512 shadow remember=5 use_authtok",
+				"password required pam_deny.so",
+			},
+		},
+		{
+			name:     "no anchor",
+			in:       []string{"#password sufficient pam_unix.so", "password required pam_permit.so"},
+			remember: 5,
+			wantErr:  "未找到 password pam_unix.so 或 pam_deny.so 行",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := testPolicy
+			policy.Remember = tt.remember
+			got, err := ensurePasswordPamLines(append([]string(nil), tt.in...), policy)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Plan is the reviewable outcome of a dry run: the final content change of
+// every file (however many helpers rewrote it) plus the commands and
+// runtime writes, in order. Hash identifies the plan for approval.
+type Plan struct {
+	Hash    string          `json:"hash"`
+	Files   []FilePlan      `json:"files"`
+	Actions []PlannedChange `json:"actions"`
+}
+
+type FilePlan struct {
+	Path         string   `json:"path"`
+	Action       string   `json:"action"` // create or modify
+	Items        []string `json:"items"`
+	SHA256Before string   `json:"sha256_before,omitempty"`
+	SHA256After  string   `json:"sha256_after"`
+	Diff         string   `json:"diff"`
+	PAM          bool     `json:"pam,omitempty"`
+}
+
+func buildPlan(changes []PlannedChange) *Plan {
+	plan := &Plan{Files: []FilePlan{}, Actions: []PlannedChange{}}
+	index := map[string]int{}
+	first := map[string]PlannedChange{}
+	final := map[string]string{}
+	for _, change := range changes {
+		if change.Kind != "file" {
+			plan.Actions = append(plan.Actions, PlannedChange{Item: change.Item, Kind: change.Kind, Path: change.Path, New: change.New, Argv: change.Argv})
+			continue
+		}
+		i, seen := index[change.Path]
+		if !seen {
+			i = len(plan.Files)
+			index[change.Path] = i
+			first[change.Path] = change
+			plan.Files = append(plan.Files, FilePlan{Path: change.Path, Items: []string{}})
+		}
+		if !containsString(plan.Files[i].Items, change.Item) {
+			plan.Files[i].Items = append(plan.Files[i].Items, change.Item)
+		}
+		final[change.Path] = change.New
+	}
+	kept := plan.Files[:0]
+	for _, fp := range plan.Files {
+		orig := first[fp.Path]
+		after := final[fp.Path]
+		if orig.Existed && orig.Old == after {
+			continue
+		}
+		fp.Action = "modify"
+		if orig.Existed {
+			fp.SHA256Before = sha256Hex([]byte(orig.Old))
+		} else {
+			fp.Action = "create"
+		}
+		fp.SHA256After = sha256Hex([]byte(after))
+		fp.Diff = unifiedDiff(fp.Path, orig.Old, after, orig.Existed)
+		fp.PAM = isPAMPath(fp.Path)
+		kept = append(kept, fp)
+	}
+	plan.Files = kept
+	plan.Hash = plan.computeHash()
+	return plan
+}
+
+// computeHash covers what would change on disk and what would run, not
+// the run ID or item outcomes, so a re-computed plan matches an approved
+// one exactly when the host is still in the reviewed state.
+func (p *Plan) computeHash() string {
+	type fileKey struct {
+		Path   string `json:"path"`
+		Before string `json:"before"`
+		After  string `json:"after"`
+	}
+	keys := struct {
+		Files   []fileKey       `json:"files"`
+		Actions []PlannedChange `json:"actions"`
+	}{Files: []fileKey{}, Actions: p.Actions}
+	for _, fp := range p.Files {
+		keys.Files = append(keys.Files, fileKey{Path: fp.Path, Before: fp.SHA256Before, After: fp.SHA256After})
+	}
+	data, _ := json.Marshal(keys)
+	return "sha256:" + sha256Hex(data)
+}
+
+func (p *Plan) touchesPAM() bool {
+	for _, fp := range p.Files {
+		if fp.PAM {
+			return true
+		}
+	}
+	return false
+}
+
+func isPAMPath(path string) bool {
+	return strings.HasPrefix(path, "/etc/pam.d/") || strings.HasPrefix(path, "/etc/security/")
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Profile holds every tunable threshold of the baseline. Fields omitted
+// from a profile file keep the built-in defaults.
+type Profile struct {
+	Name       string            `json:"name"`
+	Password   PasswordProfile   `json:"password"`
+	LockScreen LockScreenProfile `json:"lock_screen"`
+	RiskyPorts []int             `json:"risky_ports"`
+	AuditRules []string          `json:"audit_rules"`
+	Items      map[string]bool   `json:"items"`
+	Timeouts   map[string]int    `json:"timeouts"`
+	Weights    map[string]int    `json:"weights"`
+	// XCCDFRules maps item IDs to the rule IDs of a site's SCAP content;
+	// unmapped items get xccdf_cn.xc-baseline_rule_<item>.
+	XCCDFRules map[string]string `json:"xccdf_rules,omitempty"`
+
+	hash string
+}
+
+type PasswordProfile struct {
+	MaxDays    int `json:"max_days"`
+	MinDays    int `json:"min_days"`
+	MinLen     int `json:"min_len"`
+	MinClass   int `json:"min_class"`
+	Remember   int `json:"remember"`
+	Deny       int `json:"deny"`
+	UnlockTime int `json:"unlock_time"`
+}
+
+type LockScreenProfile struct {
+	IdleDelay int `json:"idle_delay"`
+	LockDelay int `json:"lock_delay"`
+}
+
+type ProfileRef struct {
+	Name string `json:"name"`
+	Hash string `json:"hash"`
+}
+
+func defaultProfile() *Profile {
+	p := &Profile{
+		Name: "default",
+		Password: PasswordProfile{
+			MaxDays:    90,
+			MinDays:    1,
+			MinLen:     10,
+			MinClass:   4,
+			Remember:   5,
+			Deny:       5,
+			UnlockTime: 600,
+		},
+		LockScreen: LockScreenProfile{
+			IdleDelay: 900,
+			LockDelay: 0,
+		},
+		RiskyPorts: []int{22, 23, 135, 137, 138, 139, 445, 455, 3389, 4899},
+		AuditRules: []string{},
+		Weights:    map[string]int{"high": 3, "medium": 2, "low": 1},
+	}
+	data, _ := json.Marshal(p)
+	p.hash = sha256Hex(data)
+	return p
+}
+
+// loadProfile reads a JSON or YAML profile. YAML is chosen by extension
+// (.yaml/.yml) or when the content does not start with '{'.
+func loadProfile(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	raw := data
+	ext := strings.ToLower(filepath.Ext(path))
+	trimmed := bytes.TrimSpace(data)
+	if ext == ".yaml" || ext == ".yml" || (ext != ".json" && !bytes.HasPrefix(trimmed, []byte("{"))) {
+		raw, err = yamlToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	p := defaultProfile()
+	p.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(p); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	p.hash = sha256Hex(data)
+	return p, nil
+}
+
+func (p *Profile) validate() error {
+	pw := p.Password
+	if pw.MaxDays <= 0 || pw.MinDays < 0 || pw.MinLen <= 0 {
+		return errors.New("password.max_days/min_len 必须大于0，min_days 不能为负")
+	}
+	if pw.MinDays > pw.MaxDays {
+		return errors.New("password.min_days 不能大于 max_days")
+	}
+	if pw.MinClass < 0 || pw.MinClass > 4 {
+		return errors.New("password.min_class 取值范围 0-4")
+	}
+	if pw.Remember < 0 || pw.Deny < 0 || pw.UnlockTime < 0 {
+		return errors.New("password.remember/deny/unlock_time 不能为负")
+	}
+	if p.LockScreen.IdleDelay <= 0 || p.LockScreen.LockDelay < 0 {
+		return errors.New("lock_screen.idle_delay 必须大于0，lock_delay 不能为负")
+	}
+	for _, port := range p.RiskyPorts {
+		if port <= 0 || port > 65535 {
+			return fmt.Errorf("risky_ports 包含无效端口: %d", port)
+		}
+	}
+	for id, seconds := range p.Timeouts {
+		if seconds <= 0 {
+			return fmt.Errorf("timeouts.%s 必须大于0", id)
+		}
+	}
+	for severity, w := range p.Weights {
+		if !containsString(severityLevels, severity) {
+			return fmt.Errorf("weights 仅支持 high/medium/low: %s", severity)
+		}
+		if w < 0 {
+			return fmt.Errorf("weights.%s 不能为负", severity)
+		}
+	}
+	rules := map[string]string{}
+	for _, id := range mapKeys(p.XCCDFRules) {
+		rule := p.XCCDFRules[id]
+		if !xccdfRuleID.MatchString(rule) {
+			return fmt.Errorf("xccdf_rules.%s 须为 xccdf_<命名空间>_rule_<名称> 格式: %s", id, rule)
+		}
+		if other, ok := rules[rule]; ok {
+			return fmt.Errorf("xccdf_rules.%s 与 %s 映射到同一规则: %s", id, other, rule)
+		}
+		rules[rule] = id
+	}
+	for _, ids := range [][]string{mapKeys(p.Items), mapKeys(p.Timeouts), mapKeys(p.XCCDFRules)} {
+		for _, id := range ids {
+			if _, ok := findChecker(id); !ok {
+				fmt.Fprintf(os.Stderr, "基线配置引用了未知检查项: %s\n", id)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Profile) itemEnabled(id string) bool {
+	enabled, ok := p.Items[id]
+	return !ok || enabled
+}
+
+func (p *Profile) ref() ProfileRef {
+	return ProfileRef{Name: p.Name, Hash: "sha256:" + p.hash}
+}
+
+func (p *Profile) riskyPortsLabel() string {
+	parts := make([]string, 0, len(p.RiskyPorts))
+	for _, port := range p.RiskyPorts {
+		parts = append(parts, fmt.Sprintf("%d", port))
+	}
+	return strings.Join(parts, "/")
+}
+
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
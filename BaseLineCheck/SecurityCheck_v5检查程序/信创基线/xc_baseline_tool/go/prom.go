@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// --format prom writes metrics for node_exporter's textfile collector so
+// baseline regressions can be alerted on. Item status is a state set: one
+// series per status, 1 for the current one, so a change shows up as a
+// value change rather than a vanished series.
+
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func promLabels(pairs ...string) string {
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, pairs[i], promLabelEscaper.Replace(pairs[i+1])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func writePromReport(out io.Writer, payload Output, now time.Time) error {
+	var b strings.Builder
+	metric := func(name, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+	metric("xc_baseline_info", "检查环境，值恒为1")
+	fmt.Fprintf(&b, "xc_baseline_info%s 1\n", promLabels("os", payload.OS, "profile", payload.Profile.Name, "profile_hash", payload.Profile.Hash, "root", payload.Root))
+
+	metric("xc_baseline_item_status", "检查项状态，当前状态为1")
+	for _, item := range payload.Items {
+		for _, status := range statusOrder {
+			value := 0
+			if item.Status == status {
+				value = 1
+			}
+			fmt.Fprintf(&b, "xc_baseline_item_status%s %d\n", promLabels("id", item.ID, "category", item.Category, "severity", item.Severity, "status", status), value)
+		}
+	}
+	metric("xc_baseline_item_duration_seconds", "检查项耗时（秒）")
+	for _, item := range payload.Items {
+		fmt.Fprintf(&b, "xc_baseline_item_duration_seconds%s %.3f\n", promLabels("id", item.ID), float64(item.DurationMS)/1000)
+	}
+
+	sum := payload.Summary
+	metric("xc_baseline_items", "各状态检查项数量")
+	for _, status := range statusOrder {
+		fmt.Fprintf(&b, "xc_baseline_items%s %d\n", promLabels("status", status), sum.Counts[status])
+	}
+	// Without pass or fail items there is no score; NaN keeps the series.
+	score := math.NaN()
+	if sum.Total > 0 {
+		score = sum.Score
+	}
+	metric("xc_baseline_score", "合规得分（0-100，按严重级别加权）")
+	fmt.Fprintf(&b, "xc_baseline_score %s\n", promFloat(score))
+	metric("xc_baseline_check_duration_seconds", "整次检查耗时（秒）")
+	fmt.Fprintf(&b, "xc_baseline_check_duration_seconds %.3f\n", float64(payload.DurationMS)/1000)
+	metric("xc_baseline_last_run_timestamp_seconds", "最近一次检查完成时间（Unix 时间戳）")
+	fmt.Fprintf(&b, "xc_baseline_last_run_timestamp_seconds %d\n", now.Unix())
+
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+func promFloat(v float64) string {
+	if math.IsNaN(v) {
+		return "NaN"
+	}
+	return fmt.Sprintf("%g", v)
+}
+
+// writeFileAtomic writes beside path and renames over it, so a collector
+// never reads a half-written file. The temporary name does not end in
+// .prom and is ignored by the textfile collector.
+func writeFileAtomic(path string, mode os.FileMode, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A bundle captures everything one run observed so the same checks can be
+// replayed elsewhere:
+//
+//	manifest.json  commands, PATH lookups, stat/readdir results, host facts
+//	files/<path>   verbatim copies of every file the checks read
+//	result.json    the verdicts produced while recording
+const bundleVersion = 1
+
+type bundleManifest struct {
+	Version  int                         `json:"version"`
+	Created  string                      `json:"created"`
+	Live     bool                        `json:"live"`
+	Root     string                      `json:"root,omitempty"`
+	IsRoot   bool                        `json:"is_root"`
+	Commands []commandRecord             `json:"commands"`
+	Lookups  map[string]string           `json:"lookups"`
+	Files    map[string]bool             `json:"files"`
+	Stats    map[string]statRecord       `json:"stats"`
+	Dirs     map[string][]dirEntryRecord `json:"dirs"`
+}
+
+type commandRecord struct {
+	Argv     []string `json:"argv"`
+	Output   string   `json:"output"`
+	ExitCode int      `json:"exit_code"`
+}
+
+type statRecord struct {
+	Exists bool        `json:"exists"`
+	IsDir  bool        `json:"is_dir,omitempty"`
+	Mode   fs.FileMode `json:"mode,omitempty"`
+	Size   int64       `json:"size,omitempty"`
+}
+
+type dirEntryRecord struct {
+	Name  string `json:"name"`
+	IsDir bool   `json:"is_dir,omitempty"`
+}
+
+func newBundleManifest() *bundleManifest {
+	return &bundleManifest{
+		Version:  bundleVersion,
+		Commands: []commandRecord{},
+		Lookups:  map[string]string{},
+		Files:    map[string]bool{},
+		Stats:    map[string]statRecord{},
+		Dirs:     map[string][]dirEntryRecord{},
+	}
+}
+
+func bundleFilePath(dir, p string) string {
+	return filepath.Join(dir, "files", filepath.Clean("/"+p))
+}
+
+// recordingSystem passes every call through to inner and keeps a copy.
+type recordingSystem struct {
+	inner System
+	dir   string
+
+	mu       sync.Mutex
+	manifest *bundleManifest
+	err      error
+}
+
+func newRecordingSystem(inner System, dir string) (*recordingSystem, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "files"), 0700); err != nil {
+		return nil, err
+	}
+	return &recordingSystem{inner: inner, dir: dir, manifest: newBundleManifest()}, nil
+}
+
+func (r *recordingSystem) Live() bool {
+	return r.inner.Live()
+}
+
+func (r *recordingSystem) ReadFile(p string) ([]byte, error) {
+	data, err := r.inner.ReadFile(p)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.manifest.Files[p] = err == nil
+	if err == nil {
+		target := bundleFilePath(r.dir, p)
+		if mkErr := os.MkdirAll(filepath.Dir(target), 0700); mkErr != nil {
+			r.keepErr(mkErr)
+		} else {
+			r.keepErr(os.WriteFile(target, data, 0600))
+		}
+	}
+	return data, err
+}
+
+func (r *recordingSystem) Stat(p string) (os.FileInfo, error) {
+	info, err := r.inner.Stat(p)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec := statRecord{Exists: err == nil}
+	if err == nil {
+		rec.IsDir = info.IsDir()
+		rec.Mode = info.Mode()
+		rec.Size = info.Size()
+	}
+	r.manifest.Stats[p] = rec
+	return info, err
+}
+
+func (r *recordingSystem) ReadDir(p string) ([]os.DirEntry, error) {
+	entries, err := r.inner.ReadDir(p)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.manifest.Stats[p] = statRecord{Exists: false}
+		return entries, err
+	}
+	list := make([]dirEntryRecord, 0, len(entries))
+	for _, entry := range entries {
+		list = append(list, dirEntryRecord{Name: entry.Name(), IsDir: entry.IsDir()})
+	}
+	r.manifest.Dirs[p] = list
+	return entries, err
+}
+
+func (r *recordingSystem) LookPath(name string) (string, error) {
+	path, err := r.inner.LookPath(name)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.manifest.Lookups[name] = ""
+	} else {
+		r.manifest.Lookups[name] = path
+	}
+	return path, err
+}
+
+func (r *recordingSystem) Run(ctx context.Context, name string, args ...string) (string, int) {
+	out, code := r.inner.Run(ctx, name, args...)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.manifest.Commands = append(r.manifest.Commands, commandRecord{
+		Argv:     append([]string{name}, args...),
+		Output:   out,
+		ExitCode: code,
+	})
+	return out, code
+}
+
+func (r *recordingSystem) keepErr(err error) {
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+// save writes the manifest and the verdicts of this run into the bundle.
+func (r *recordingSystem) save(rc *RunContext, payload Output) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.manifest.Created = time.Now().Format(time.RFC3339)
+	r.manifest.Live = r.inner.Live()
+	r.manifest.Root = rc.scanRoot()
+	r.manifest.IsRoot = rc.IsRoot
+	if err := writeJSONFile(filepath.Join(r.dir, "manifest.json"), r.manifest); err != nil {
+		return err
+	}
+	return writeJSONFile(filepath.Join(r.dir, "result.json"), payload)
+}
+
+// replaySystem answers every call from a recorded bundle and never touches
+// the local host.
+type replaySystem struct {
+	dir      string
+	manifest *bundleManifest
+
+	mu      sync.Mutex
+	pending map[string][]commandRecord
+}
+
+func loadReplaySystem(dir string) (*replaySystem, error) {
+	manifest := newBundleManifest()
+	if err := readJSONFile(filepath.Join(dir, "manifest.json"), manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Version != bundleVersion {
+		return nil, fmt.Errorf("不支持的录制包版本: %d", manifest.Version)
+	}
+	pending := map[string][]commandRecord{}
+	for _, cmd := range manifest.Commands {
+		key := strings.Join(cmd.Argv, "\x00")
+		pending[key] = append(pending[key], cmd)
+	}
+	return &replaySystem{dir: dir, manifest: manifest, pending: pending}, nil
+}
+
+func (r *replaySystem) Live() bool {
+	return r.manifest.Live
+}
+
+func (r *replaySystem) ReadFile(p string) ([]byte, error) {
+	if !r.manifest.Files[p] {
+		return nil, &os.PathError{Op: "open", Path: p, Err: os.ErrNotExist}
+	}
+	return os.ReadFile(bundleFilePath(r.dir, p))
+}
+
+func (r *replaySystem) Stat(p string) (os.FileInfo, error) {
+	if rec, ok := r.manifest.Stats[p]; ok {
+		if !rec.Exists {
+			return nil, &os.PathError{Op: "stat", Path: p, Err: os.ErrNotExist}
+		}
+		return recordedFileInfo{name: filepath.Base(p), rec: rec}, nil
+	}
+	if r.manifest.Files[p] {
+		return os.Stat(bundleFilePath(r.dir, p))
+	}
+	if _, ok := r.manifest.Dirs[p]; ok {
+		return recordedFileInfo{name: filepath.Base(p), rec: statRecord{Exists: true, IsDir: true, Mode: fs.ModeDir | 0755}}, nil
+	}
+	return nil, &os.PathError{Op: "stat", Path: p, Err: os.ErrNotExist}
+}
+
+func (r *replaySystem) ReadDir(p string) ([]os.DirEntry, error) {
+	list, ok := r.manifest.Dirs[p]
+	if !ok {
+		return nil, &os.PathError{Op: "readdir", Path: p, Err: os.ErrNotExist}
+	}
+	entries := make([]os.DirEntry, 0, len(list))
+	for _, entry := range list {
+		mode := fs.FileMode(0644)
+		if entry.IsDir {
+			mode = fs.ModeDir | 0755
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(recordedFileInfo{
+			name: entry.Name,
+			rec:  statRecord{Exists: true, IsDir: entry.IsDir, Mode: mode},
+		}))
+	}
+	return entries, nil
+}
+
+func (r *replaySystem) LookPath(name string) (string, error) {
+	if path := r.manifest.Lookups[name]; path != "" {
+		return path, nil
+	}
+	return "", &os.PathError{Op: "lookpath", Path: name, Err: os.ErrNotExist}
+}
+
+// Run returns recorded invocations of the same argv in order, repeating the
+// last one if the checks call it more often than during recording.
+func (r *replaySystem) Run(ctx context.Context, name string, args ...string) (string, int) {
+	key := strings.Join(append([]string{name}, args...), "\x00")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	queue := r.pending[key]
+	if len(queue) == 0 {
+		return "", 127
+	}
+	cmd := queue[0]
+	if len(queue) > 1 {
+		r.pending[key] = queue[1:]
+	}
+	return cmd.Output, cmd.ExitCode
+}
+
+// compareRecorded reports items whose replayed status differs from the
+// verdict stored when the bundle was recorded.
+func (r *replaySystem) compareRecorded(results []OutputItem) ([]string, error) {
+	var recorded Output
+	if err := readJSONFile(filepath.Join(r.dir, "result.json"), &recorded); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	before := map[string]string{}
+	for _, item := range recorded.Items {
+		before[item.ID] = item.Status
+	}
+	diffs := []string{}
+	for _, item := range results {
+		if status, ok := before[item.ID]; ok && status != item.Status {
+			diffs = append(diffs, fmt.Sprintf("%s: %s -> %s", item.ID, status, item.Status))
+		}
+	}
+	sort.Strings(diffs)
+	return diffs, nil
+}
+
+type recordedFileInfo struct {
+	name string
+	rec  statRecord
+}
+
+func (i recordedFileInfo) Name() string       { return i.name }
+func (i recordedFileInfo) Size() int64        { return i.rec.Size }
+func (i recordedFileInfo) Mode() fs.FileMode  { return i.rec.Mode }
+func (i recordedFileInfo) ModTime() time.Time { return time.Time{} }
+func (i recordedFileInfo) IsDir() bool        { return i.rec.IsDir }
+func (i recordedFileInfo) Sys() interface{}   { return nil }
+
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
+
+func readJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
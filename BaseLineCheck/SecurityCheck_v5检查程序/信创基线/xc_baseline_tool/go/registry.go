@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Checker is a single baseline item. Built-in items are registered in
+// catalog.go; site-specific items can live in their own file and call
+// Register from an init function.
+type Checker interface {
+	Meta() CheckMeta
+	Check(rc *RunContext) Result
+}
+
+type CheckMeta struct {
+	ID        string
+	Name      string
+	Desc      string
+	Expected  string
+	Category  string
+	Severity  string
+	Distros   []string
+	NeedsRoot bool
+	// Live items depend on running state (services, sockets, /proc) and
+	// cannot be judged from an offline root filesystem.
+	Live     bool
+	CanApply bool
+	// Timeout overrides the run's default per-item timeout when set.
+	Timeout time.Duration
+}
+
+// RunContext is shared by every check in one run. Each item gets a shallow
+// copy carrying its own deadline.
+type RunContext struct {
+	OS          OSInfo
+	Distro      DistroKind
+	IsRoot      bool
+	Profile     *Profile
+	Sys         System
+	Jobs        int
+	ItemTimeout time.Duration
+	// Tx is set while remediating; file writes and mutating commands go
+	// through it so they can be planned, journaled and rolled back.
+	Tx *Tx
+	// Waivers accept the risk of specific failures until they expire.
+	Waivers []Waiver
+	// Inspector is named on the html/docx reports.
+	Inspector string
+
+	ctx context.Context
+}
+
+var (
+	registry     []Checker
+	registryByID = map[string]Checker{}
+)
+
+func Register(c Checker) {
+	meta := c.Meta()
+	if meta.ID == "" {
+		panic("baseline item registered without ID")
+	}
+	if _, ok := registryByID[meta.ID]; ok {
+		panic(fmt.Sprintf("baseline item %q registered twice", meta.ID))
+	}
+	registry = append(registry, c)
+	registryByID[meta.ID] = c
+}
+
+func registeredCheckers() []Checker {
+	out := make([]Checker, len(registry))
+	copy(out, registry)
+	return out
+}
+
+func findChecker(id string) (Checker, bool) {
+	c, ok := registryByID[id]
+	return c, ok
+}
+
+// expecter is implemented by items whose expected value depends on the
+// active profile.
+type expecter interface {
+	ExpectedFor(rc *RunContext) string
+}
+
+// Fixer is implemented by items that can remediate themselves. Fix must
+// change files only through rc.writeFile/writeLines and run mutating
+// commands through rc.mutate.
+type Fixer interface {
+	Fix(rc *RunContext) error
+}
+
+type funcChecker struct {
+	meta     CheckMeta
+	check    func(rc *RunContext) Result
+	expected func(rc *RunContext) string
+	fix      func(rc *RunContext) error
+}
+
+func (f funcChecker) Meta() CheckMeta {
+	meta := f.meta
+	meta.CanApply = f.fix != nil
+	return meta
+}
+
+func (f funcChecker) Check(rc *RunContext) Result { return f.check(rc) }
+
+func (f funcChecker) ExpectedFor(rc *RunContext) string {
+	if f.expected == nil {
+		return f.meta.Expected
+	}
+	return f.expected(rc)
+}
+
+func (f funcChecker) Fix(rc *RunContext) error {
+	if f.fix == nil {
+		return fmt.Errorf("检查项 %s 不支持自动修复", f.meta.ID)
+	}
+	return f.fix(rc)
+}
+
+func registerFunc(meta CheckMeta, check func(rc *RunContext) Result) {
+	Register(funcChecker{meta: meta, check: check})
+}
+
+func newRunContext(ctx context.Context, profile *Profile, sys System) *RunContext {
+	info := detectOSInfo(sys)
+	return &RunContext{
+		OS:          info,
+		Distro:      detectDistroKind(info),
+		IsRoot:      os.Geteuid() == 0,
+		Profile:     profile,
+		Sys:         sys,
+		Jobs:        defaultJobs,
+		ItemTimeout: defaultItemTimeout,
+		ctx:         ctx,
+	}
+}
+
+func (rc *RunContext) context() context.Context {
+	if rc.ctx == nil {
+		return context.Background()
+	}
+	return rc.ctx
+}
+
+// enabledCheckers drops the items switched off by the profile.
+func enabledCheckers(rc *RunContext, checkers []Checker) []Checker {
+	out := []Checker{}
+	for _, c := range checkers {
+		if rc.Profile.itemEnabled(c.Meta().ID) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Selection narrows the run from the command line. Empty fields select
+// everything; --only keeps catalog order rather than argument order.
+type Selection struct {
+	Only []string
+	Skip []string
+	Tags []string
+}
+
+func splitList(value string) []string {
+	out := []string{}
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func (s Selection) apply(checkers []Checker) ([]Checker, error) {
+	categories := map[string]bool{}
+	for _, c := range registeredCheckers() {
+		categories[c.Meta().Category] = true
+	}
+	for _, id := range append(append([]string{}, s.Only...), s.Skip...) {
+		if _, ok := findChecker(id); !ok {
+			return nil, fmt.Errorf("未知检查项: %s", id)
+		}
+	}
+	for _, tag := range s.Tags {
+		if !categories[tag] {
+			return nil, fmt.Errorf("未知分类: %s（可用: %s）", tag, strings.Join(mapKeys(categories), ","))
+		}
+	}
+	out := []Checker{}
+	for _, c := range checkers {
+		meta := c.Meta()
+		if len(s.Only) > 0 && !containsString(s.Only, meta.ID) {
+			continue
+		}
+		if containsString(s.Skip, meta.ID) {
+			continue
+		}
+		if len(s.Tags) > 0 && !containsString(s.Tags, meta.Category) {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// appliesTo matches the item's distro list against the os-release ID,
+// ID_LIKE and the detected distro family. An empty list means all distros.
+func (m CheckMeta) appliesTo(rc *RunContext) bool {
+	if len(m.Distros) == 0 {
+		return true
+	}
+	tags := map[string]bool{}
+	for _, field := range append([]string{rc.OS.ID}, strings.Fields(rc.OS.IDLike)...) {
+		if field != "" {
+			tags[strings.ToLower(field)] = true
+		}
+	}
+	if rc.Distro.IsUOS {
+		tags["uos"] = true
+	}
+	if rc.Distro.IsKylin {
+		tags["kylin"] = true
+	}
+	if rc.Distro.IsNeoKylin {
+		tags["neokylin"] = true
+	}
+	for _, d := range m.Distros {
+		if tags[strings.ToLower(d)] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// FixOutcome is the result of remediating one item.
+//
+//	planned      dry run, changes listed in the plan
+//	fixed        fix applied and the item's check now passes
+//	rolled_back  fix failed or did not verify; its file changes were restored
+//	failed       dry run could not plan the fix
+//	skipped      already compliant, waived, not fixable or declined
+type FixOutcome struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Before  string `json:"before"`
+	After   string `json:"after,omitempty"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+type ApplyReport struct {
+	RunID  string       `json:"run_id"`
+	DryRun bool         `json:"dry_run"`
+	Items  []FixOutcome `json:"items"`
+	Plan   *Plan        `json:"plan,omitempty"`
+}
+
+// remediate fixes the items whose status in before is not pass, one at a
+// time. confirm, when set, is asked before each fix.
+func remediate(rc *RunContext, checkers []Checker, before []OutputItem, confirm func(OutputItem) bool) ApplyReport {
+	tx := rc.Tx
+	report := ApplyReport{RunID: tx.ID, DryRun: tx.DryRun, Items: []FixOutcome{}}
+	for i, c := range checkers {
+		item := before[i]
+		outcome := FixOutcome{ID: item.ID, Name: item.Name, Before: item.Status, Status: "skipped"}
+		fixer, ok := c.(Fixer)
+		switch {
+		case !ok || !item.CanApply:
+			outcome.Message = "不支持自动修复"
+		case item.Status == "pass" || item.Status == "info" || item.Status == "not_applicable":
+			outcome.Message = "已符合基线，无需修复"
+		case item.Status == "waived":
+			outcome.Message = "已豁免，不修复"
+		case confirm != nil && !confirm(item):
+			outcome.Message = "已跳过"
+		case tx.failed() != nil:
+			outcome.Message = "事务日志写入失败，已停止修复"
+		default:
+			outcome = applyFix(rc, c, fixer, outcome)
+		}
+		report.Items = append(report.Items, outcome)
+	}
+	if tx.DryRun {
+		report.Plan = buildPlan(tx.Plan)
+	}
+	return report
+}
+
+func applyFix(rc *RunContext, c Checker, fixer Fixer, outcome FixOutcome) FixOutcome {
+	tx := rc.Tx
+	tx.begin(outcome.ID)
+	err := fixer.Fix(rc)
+	if err == nil {
+		err = tx.failed()
+	}
+	if tx.DryRun {
+		if err != nil {
+			outcome.Status = "failed"
+			outcome.Message = "修复预演失败: " + err.Error()
+			return outcome
+		}
+		outcome.Status = "planned"
+		return outcome
+	}
+	if err == nil {
+		after := runCheckerTimed(rc, c, itemMeta(rc, c))
+		outcome.After = after.Status
+		if after.Status == "pass" {
+			outcome.Status = "fixed"
+			return outcome
+		}
+		err = fmt.Errorf("修复后复核未通过（%s: %s）", after.Status, after.Current)
+	}
+	outcome.Status = "rolled_back"
+	outcome.Message = err.Error()
+	restored, rbErr := tx.rollbackItem(outcome.ID)
+	if rbErr != nil {
+		outcome.Message += "；回滚失败: " + rbErr.Error()
+	} else if len(restored) > 0 {
+		outcome.Message += "；已恢复: " + strings.Join(restored, ", ")
+	}
+	if n := tx.commandsFor(outcome.ID); n > 0 {
+		outcome.Message += fmt.Sprintf("；已执行的%d条命令无法自动撤销", n)
+	}
+	return outcome
+}
+
+// approvePlan computes what a real run would change, without touching the
+// host, and refuses it when it does not match the approved hash. Changes
+// to PAM always need an approved plan.
+func approvePlan(rc *RunContext, checkers []Checker, before []OutputItem, approved string) error {
+	dry, err := newTx(true)
+	if err != nil {
+		return err
+	}
+	preview := *rc
+	preview.Tx = dry
+	preview.Sys = txSystem{System: rc.Sys, tx: dry}
+	plan := remediate(&preview, checkers, before, nil).Plan
+	if approved == "" {
+		if plan.touchesPAM() {
+			return fmt.Errorf("修复将修改PAM/认证配置，请先用 --dry-run 审阅并以 --approved-plan %s 执行", plan.Hash)
+		}
+		return nil
+	}
+	if approved != plan.Hash {
+		return fmt.Errorf("当前修复计划 %s 与批准的计划不一致，请重新审阅", plan.Hash)
+	}
+	return nil
+}
+
+// exitCode is exitPass when every attempted fix succeeded or was planned.
+func (r ApplyReport) exitCode() int {
+	for _, item := range r.Items {
+		if item.Status == "rolled_back" || item.Status == "failed" {
+			return exitFail
+		}
+	}
+	return exitPass
+}
+
+func promptFix(in *bufio.Reader) func(OutputItem) bool {
+	return func(item OutputItem) bool {
+		fmt.Printf("是否修复 [%s] %s（当前: %s）？[y/N]: ", item.ID, item.Name, item.Status)
+		answer, _ := in.ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		return answer == "y" || answer == "yes"
+	}
+}
+
+func printApplyReport(report ApplyReport, jsonOut bool, outputFile string) error {
+	var out io.Writer = os.Stdout
+	if outputFile != "" {
+		file, err := os.Create(outputFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	if jsonOut {
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	if report.DryRun {
+		fmt.Fprintln(out, "修复预演（未修改系统）")
+	}
+	fmt.Fprintln(out, "============================================================")
+	for _, item := range report.Items {
+		status := item.Status
+		switch status {
+		case "fixed":
+			status = colorize("green", status)
+		case "rolled_back", "failed":
+			status = colorize("red", status)
+		}
+		fmt.Fprintf(out, "[%s] %s: %s", item.ID, item.Name, status)
+		if item.Message != "" {
+			fmt.Fprintf(out, "（%s）", item.Message)
+		}
+		fmt.Fprintln(out)
+	}
+	if plan := report.Plan; plan != nil {
+		for _, fp := range plan.Files {
+			fmt.Fprintln(out, "------------------------------------------------------------")
+			action := "将修改"
+			if fp.Action == "create" {
+				action = "将创建"
+			}
+			fmt.Fprintf(out, "%s %s（%s）\n", action, fp.Path, strings.Join(fp.Items, ", "))
+			fmt.Fprint(out, fp.Diff)
+		}
+		if len(plan.Actions) > 0 {
+			fmt.Fprintln(out, "------------------------------------------------------------")
+		}
+		for _, change := range plan.Actions {
+			switch change.Kind {
+			case "proc":
+				fmt.Fprintf(out, "[%s] 将写入 %s = %s\n", change.Item, change.Path, change.New)
+			case "command":
+				fmt.Fprintf(out, "[%s] 将执行 %s\n", change.Item, planCommandLine(change.Argv))
+			}
+		}
+		fmt.Fprintln(out, "------------------------------------------------------------")
+		fmt.Fprintf(out, "计划摘要: %s\n", plan.Hash)
+		if plan.touchesPAM() {
+			fmt.Fprintln(out, "计划包含PAM/认证配置变更，执行时需 --approved-plan "+plan.Hash)
+		}
+	} else {
+		fmt.Fprintf(out, "运行ID: %s（回滚: xc-baseline-go --rollback %s）\n", report.RunID, report.RunID)
+	}
+	fmt.Fprintln(out, "============================================================")
+	return nil
+}
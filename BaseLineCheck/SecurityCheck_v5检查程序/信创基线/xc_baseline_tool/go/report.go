@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Reports handed to terminal owners (--format html) share this view of a
+// check: who was checked, the score and each item with its guidance.
+
+var statusLabels = map[string]string{
+	"pass":           "通过",
+	"fail":           "不通过",
+	"waived":         "已豁免",
+	"manual":         "需人工确认",
+	"info":           "信息",
+	"not_applicable": "不适用",
+	"timeout":        "超时",
+	"error":          "错误",
+}
+
+// statusOrder lists every status, most urgent first.
+var statusOrder = []string{"fail", "waived", "manual", "timeout", "error", "pass", "info", "not_applicable"}
+
+var severityLabels = map[string]string{
+	"high":   "高",
+	"medium": "中",
+	"low":    "低",
+}
+
+func labelOf(labels map[string]string, key string) string {
+	if label, ok := labels[key]; ok {
+		return label
+	}
+	return key
+}
+
+type HostIdentity struct {
+	Hostname  string
+	Addresses []string
+	OS        string
+	Kernel    string
+	Root      string
+	CheckedAt time.Time
+}
+
+func hostIdentity(rc *RunContext, payload Output) HostIdentity {
+	host := HostIdentity{Hostname: readHostname(rc), OS: payload.OS, Root: payload.Root, CheckedAt: time.Now()}
+	if data, err := rc.Sys.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		host.Kernel = strings.TrimSpace(string(data))
+	}
+	if rc.Sys.Live() {
+		ctx, cancel := context.WithTimeout(rc.ctx, 5*time.Second)
+		defer cancel()
+		if out, code := rc.Sys.Run(ctx, "hostname", "-I"); code == 0 {
+			host.Addresses = strings.Fields(out)
+		}
+	}
+	return host
+}
+
+type ReportItem struct {
+	OutputItem
+	StatusLabel   string
+	SeverityLabel string
+	Failed        []Finding
+	Hint          string
+}
+
+type StatusCount struct {
+	Status string
+	Label  string
+	Count  int
+}
+
+type ReportView struct {
+	Inspector string
+	Host      HostIdentity
+	Output    Output
+	Items     []ReportItem
+	Counts    []StatusCount
+}
+
+func buildReportView(rc *RunContext, payload Output) ReportView {
+	view := ReportView{Inspector: rc.Inspector, Host: hostIdentity(rc, payload), Output: payload}
+	for _, item := range payload.Items {
+		ri := ReportItem{
+			OutputItem:    item,
+			StatusLabel:   labelOf(statusLabels, item.Status),
+			SeverityLabel: labelOf(severityLabels, item.Severity),
+			Failed:        failedFindings(item.Findings),
+		}
+		if item.Status == "fail" {
+			ri.Hint = manualFixHint(rc, item.ID)
+		}
+		view.Items = append(view.Items, ri)
+	}
+	for status, n := range payload.Summary.Counts {
+		view.Counts = append(view.Counts, StatusCount{Status: status, Label: labelOf(statusLabels, status), Count: n})
+	}
+	rank := func(status string) int {
+		for i, s := range statusOrder {
+			if s == status {
+				return i
+			}
+		}
+		return len(statusOrder)
+	}
+	sort.Slice(view.Counts, func(i, j int) bool { return rank(view.Counts[i].Status) < rank(view.Counts[j].Status) })
+	return view
+}
@@ -27,8 +27,7 @@ if [ ! -x "$BIN" ]; then
 fi
 
 if [ -z "${DISPLAY:-}" ] && [ -z "${WAYLAND_DISPLAY:-}" ]; then
-  "$BIN" --check
-  exit 0
+  exec "$BIN" --check
 fi
 
 export NO_AT_BRIDGE=1
@@ -92,8 +91,9 @@ echo "0) 退出"
 echo -n "请选择: "
 read -r choice
 case "$choice" in
-  "") "$BIN" --check ;;
-  1) "$BIN" --check ;;
+  # Non-zero exit codes report check verdicts; keep the window open.
+  "") "$BIN" --check || true ;;
+  1) "$BIN" --check || true ;;
   2) "$BIN" --list ;;
   0) exit 0 ;;
   *) echo "无效选择" ;;
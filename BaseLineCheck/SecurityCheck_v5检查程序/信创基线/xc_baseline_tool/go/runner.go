@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultJobs        = 4
+	defaultItemTimeout = 60 * time.Second
+)
+
+// collectResults runs the checkers on a bounded worker pool and returns
+// the items in catalog order regardless of completion order.
+func collectResults(rc *RunContext, checkers []Checker) []OutputItem {
+	parent := rc.context()
+	jobs := rc.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	results := make([]OutputItem, len(checkers))
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, c := range checkers {
+		wg.Add(1)
+		go func(i int, c Checker) {
+			defer wg.Done()
+			meta := itemMeta(rc, c)
+			select {
+			case sem <- struct{}{}:
+			case <-parent.Done():
+				results[i] = newOutputItem(meta, Result{Status: "timeout", Current: "整体检查超时，未执行"})
+				return
+			}
+			defer func() { <-sem }()
+			if parent.Err() != nil {
+				results[i] = newOutputItem(meta, Result{Status: "timeout", Current: "整体检查超时，未执行"})
+				return
+			}
+			results[i] = runCheckerTimed(rc, c, meta)
+		}(i, c)
+	}
+	wg.Wait()
+	return applyWaivers(rc.Waivers, results, time.Now())
+}
+
+func runCheckerTimed(rc *RunContext, c Checker, meta CheckMeta) OutputItem {
+	parent := rc.context()
+	timeout := rc.itemTimeout(meta)
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+	itemRC := *rc
+	itemRC.ctx = ctx
+
+	start := time.Now()
+	done := make(chan Result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- Result{Status: "error", Current: fmt.Sprintf("检查异常: %v", r)}
+			}
+		}()
+		done <- evaluateChecker(&itemRC, c, meta)
+	}()
+
+	var res Result
+	select {
+	case res = <-done:
+	case <-ctx.Done():
+	}
+	// Commands killed by the deadline return partial output; never trust
+	// a verdict computed after the context expired.
+	if ctx.Err() != nil {
+		if parent.Err() != nil {
+			res = Result{Status: "timeout", Current: "整体检查超时，未完成"}
+		} else {
+			res = Result{Status: "timeout", Current: fmt.Sprintf("检查超时（超过%s）", timeout)}
+		}
+	}
+	item := newOutputItem(meta, res)
+	item.DurationMS = time.Since(start).Milliseconds()
+	return item
+}
+
+func (rc *RunContext) itemTimeout(meta CheckMeta) time.Duration {
+	if seconds, ok := rc.Profile.Timeouts[meta.ID]; ok {
+		return time.Duration(seconds) * time.Second
+	}
+	if meta.Timeout > 0 {
+		return meta.Timeout
+	}
+	if rc.ItemTimeout > 0 {
+		return rc.ItemTimeout
+	}
+	return defaultItemTimeout
+}
+
+func itemMeta(rc *RunContext, c Checker) CheckMeta {
+	meta := c.Meta()
+	if e, ok := c.(expecter); ok {
+		meta.Expected = e.ExpectedFor(rc)
+	}
+	return meta
+}
+
+func evaluateChecker(rc *RunContext, c Checker, meta CheckMeta) Result {
+	if !meta.appliesTo(rc) {
+		return Result{Status: "not_applicable", Current: "不适用于当前系统"}
+	}
+	if meta.Live && !rc.Sys.Live() {
+		return Result{Status: "not_applicable", Current: "离线扫描不适用（需要运行时状态）"}
+	}
+	res := c.Check(rc)
+	if meta.NeedsRoot && !rc.IsRoot && (res.Status == "fail" || res.Status == "manual") {
+		res.Current += "（非root运行，结果可能不完整）"
+	}
+	return res
+}
+
+func newOutputItem(meta CheckMeta, res Result) OutputItem {
+	findings := res.Findings
+	if findings == nil {
+		findings = []Finding{}
+	}
+	return OutputItem{
+		ID:       meta.ID,
+		Name:     meta.Name,
+		Desc:     meta.Desc,
+		Expected: meta.Expected,
+		Category: meta.Category,
+		Severity: meta.Severity,
+		CanApply: meta.CanApply,
+		Status:   res.Status,
+		Current:  res.Current,
+		Findings: findings,
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import "math"
+
+var severityLevels = []string{"high", "medium", "low"}
+
+// Summary is the per-host verdict. Only pass and fail items are scored;
+// manual, info, not_applicable, timeout and error items carry no weight
+// because nothing was decided about them, and waived items are accepted
+// risks.
+type Summary struct {
+	Score  float64        `json:"score"`
+	Grade  string         `json:"grade"`
+	Earned int            `json:"earned"`
+	Total  int            `json:"total"`
+	Counts map[string]int `json:"counts"`
+}
+
+func (p *Profile) weight(severity string) int {
+	if w, ok := p.Weights[severity]; ok {
+		return w
+	}
+	return p.Weights["low"]
+}
+
+func summarize(profile *Profile, items []OutputItem) Summary {
+	sum := Summary{Counts: map[string]int{}}
+	for _, item := range items {
+		sum.Counts[item.Status]++
+		w := profile.weight(item.Severity)
+		switch item.Status {
+		case "pass":
+			sum.Earned += w
+			sum.Total += w
+		case "fail":
+			sum.Total += w
+		}
+	}
+	if sum.Total == 0 {
+		sum.Grade = "N/A"
+		return sum
+	}
+	sum.Score = math.Round(float64(sum.Earned)*1000/float64(sum.Total)) / 10
+	sum.Grade = gradeFor(sum.Score)
+	return sum
+}
+
+func gradeFor(score float64) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 75:
+		return "B"
+	case score >= 60:
+		return "C"
+	default:
+		return "D"
+	}
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// JSON results written to a file are signed into a detached
+// <output>.sig over the canonical JSON (keys sorted, no whitespace), so
+// re-indenting the file keeps the signature valid while any edited value
+// breaks it. Keys are one line "<algorithm>:<hex>": the private key is the
+// SM2 scalar or Ed25519 seed, the public key the uncompressed SM2 point or
+// the Ed25519 key.
+
+// embeddedSignKey is set at build time (build.sh, XC_SIGN_KEY) so results
+// are signed without a key file on the terminal.
+var embeddedSignKey string
+
+const (
+	algSM2     = "sm2"
+	algEd25519 = "ed25519"
+	sigSuffix  = ".sig"
+)
+
+// sm2DefaultID is the signer ID of GM/T 0009 when none is agreed.
+var sm2DefaultID = []byte("1234567812345678")
+
+var sm2Curve = func() *elliptic.CurveParams {
+	hexInt := func(s string) *big.Int {
+		n, _ := new(big.Int).SetString(s, 16)
+		return n
+	}
+	// a = p - 3, so the generic CurveParams arithmetic applies.
+	return &elliptic.CurveParams{
+		Name:    "SM2",
+		BitSize: 256,
+		P:       hexInt("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF00000000FFFFFFFFFFFFFFFF"),
+		N:       hexInt("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFF7203DF6B21C6052B53BBF40939D54123"),
+		B:       hexInt("28E9FA9E9D9F5E344D5A9E4BCF6509A7F39789F515AB8F92DDBCBD414D940E93"),
+		Gx:      hexInt("32C4AE2C1F1981195F9904466A39C9948FE30BBFF2660BE1715A4589334C74C7"),
+		Gy:      hexInt("BC3736A2F4F6779C59BDCEE36B692153D0A9877CC62A474002DF32E52139F0A0"),
+	}
+}()
+
+type SignKey struct {
+	Algorithm string
+	sm2D      *big.Int
+	ed        ed25519.PrivateKey
+}
+
+type VerifyKey struct {
+	Algorithm string
+	sm2X      *big.Int
+	sm2Y      *big.Int
+	ed        ed25519.PublicKey
+}
+
+// SignatureFile is the content of <result>.sig.
+type SignatureFile struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+	Signature string `json:"signature"`
+}
+
+func parseKeyLine(text string) (string, []byte, error) {
+	alg, value, ok := strings.Cut(strings.TrimSpace(text), ":")
+	if !ok || (alg != algSM2 && alg != algEd25519) {
+		return "", nil, errors.New("密钥格式应为 sm2:<hex> 或 ed25519:<hex>")
+	}
+	raw, err := hex.DecodeString(value)
+	if err != nil {
+		return "", nil, fmt.Errorf("密钥不是有效的十六进制: %v", err)
+	}
+	return alg, raw, nil
+}
+
+func parseSignKey(text string) (*SignKey, error) {
+	alg, raw, err := parseKeyLine(text)
+	if err != nil {
+		return nil, err
+	}
+	if alg == algEd25519 {
+		if len(raw) != ed25519.SeedSize {
+			return nil, errors.New("ed25519 私钥应为32字节")
+		}
+		return &SignKey{Algorithm: alg, ed: ed25519.NewKeyFromSeed(raw)}, nil
+	}
+	d := new(big.Int).SetBytes(raw)
+	if len(raw) != 32 || d.Sign() == 0 || d.Cmp(new(big.Int).Sub(sm2Curve.N, big.NewInt(1))) >= 0 {
+		return nil, errors.New("sm2 私钥应为32字节且位于 [1, n-2]")
+	}
+	return &SignKey{Algorithm: alg, sm2D: d}, nil
+}
+
+func parseVerifyKey(text string) (*VerifyKey, error) {
+	alg, raw, err := parseKeyLine(text)
+	if err != nil {
+		return nil, err
+	}
+	if alg == algEd25519 {
+		if len(raw) != ed25519.PublicKeySize {
+			return nil, errors.New("ed25519 公钥应为32字节")
+		}
+		return &VerifyKey{Algorithm: alg, ed: ed25519.PublicKey(raw)}, nil
+	}
+	if len(raw) != 65 || raw[0] != 4 {
+		return nil, errors.New("sm2 公钥应为65字节未压缩点（04||x||y）")
+	}
+	x, y := new(big.Int).SetBytes(raw[1:33]), new(big.Int).SetBytes(raw[33:])
+	if !sm2Curve.IsOnCurve(x, y) {
+		return nil, errors.New("sm2 公钥不在曲线上")
+	}
+	return &VerifyKey{Algorithm: alg, sm2X: x, sm2Y: y}, nil
+}
+
+func loadSignKey(path string) (*SignKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parseSignKey(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return key, nil
+}
+
+func loadVerifyKey(path string) (*VerifyKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parseVerifyKey(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return key, nil
+}
+
+func generateSignKey(alg string) (*SignKey, error) {
+	switch alg {
+	case algEd25519:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return &SignKey{Algorithm: alg, ed: priv}, nil
+	case algSM2:
+		d, err := sm2RandScalar(rand.Reader, new(big.Int).Sub(sm2Curve.N, big.NewInt(1)))
+		if err != nil {
+			return nil, err
+		}
+		return &SignKey{Algorithm: alg, sm2D: d}, nil
+	}
+	return nil, fmt.Errorf("未知的签名算法: %s（可选 sm2 / ed25519）", alg)
+}
+
+func (k *SignKey) String() string {
+	if k.Algorithm == algEd25519 {
+		return k.Algorithm + ":" + hex.EncodeToString(k.ed.Seed())
+	}
+	return k.Algorithm + ":" + hex.EncodeToString(k.sm2D.FillBytes(make([]byte, 32)))
+}
+
+func (k *SignKey) Public() *VerifyKey {
+	if k.Algorithm == algEd25519 {
+		return &VerifyKey{Algorithm: k.Algorithm, ed: k.ed.Public().(ed25519.PublicKey)}
+	}
+	x, y := sm2Curve.ScalarBaseMult(k.sm2D.FillBytes(make([]byte, 32)))
+	return &VerifyKey{Algorithm: k.Algorithm, sm2X: x, sm2Y: y}
+}
+
+func (k *VerifyKey) bytes() []byte {
+	if k.Algorithm == algEd25519 {
+		return k.ed
+	}
+	return elliptic.Marshal(sm2Curve, k.sm2X, k.sm2Y)
+}
+
+func (k *VerifyKey) String() string {
+	return k.Algorithm + ":" + hex.EncodeToString(k.bytes())
+}
+
+// KeyID names the key in signature files, so the collection side can pick
+// the matching site key.
+func (k *VerifyKey) KeyID() string {
+	sum := sha256.Sum256([]byte(k.String()))
+	return hex.EncodeToString(sum[:8])
+}
+
+func sigAlgorithm(alg string) string {
+	if alg == algSM2 {
+		return "sm2-sm3"
+	}
+	return alg
+}
+
+func (k *SignKey) Sign(msg []byte) (SignatureFile, error) {
+	sig := SignatureFile{Algorithm: sigAlgorithm(k.Algorithm), KeyID: k.Public().KeyID()}
+	if k.Algorithm == algEd25519 {
+		sig.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(k.ed, msg))
+		return sig, nil
+	}
+	pub := k.Public()
+	e := new(big.Int).SetBytes(sm2Digest(pub, msg))
+	n := sm2Curve.N
+	inv := new(big.Int).ModInverse(new(big.Int).Add(k.sm2D, big.NewInt(1)), n)
+	for {
+		kk, err := sm2RandScalar(rand.Reader, n)
+		if err != nil {
+			return sig, err
+		}
+		r, s := sm2SignWith(k.sm2D, inv, e, kk)
+		if r == nil {
+			continue
+		}
+		raw := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		sig.Signature = base64.StdEncoding.EncodeToString(raw)
+		return sig, nil
+	}
+}
+
+// sm2SignWith computes (r, s) for the ephemeral scalar kk, or nil when kk
+// must be drawn again.
+func sm2SignWith(d, inv, e, kk *big.Int) (*big.Int, *big.Int) {
+	n := sm2Curve.N
+	x1, _ := sm2Curve.ScalarBaseMult(kk.FillBytes(make([]byte, 32)))
+	r := new(big.Int).Add(e, x1)
+	r.Mod(r, n)
+	if r.Sign() == 0 || new(big.Int).Add(r, kk).Cmp(n) == 0 {
+		return nil, nil
+	}
+	s := new(big.Int).Mul(r, d)
+	s.Sub(kk, s)
+	s.Mul(s, inv)
+	s.Mod(s, n)
+	if s.Sign() == 0 {
+		return nil, nil
+	}
+	return r, s
+}
+
+func (k *VerifyKey) Verify(msg []byte, sig SignatureFile) error {
+	if sig.Algorithm != sigAlgorithm(k.Algorithm) {
+		return fmt.Errorf("签名算法 %s 与公钥 %s 不符", sig.Algorithm, k.Algorithm)
+	}
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return fmt.Errorf("签名不是有效的base64: %v", err)
+	}
+	if k.Algorithm == algEd25519 {
+		if !ed25519.Verify(k.ed, msg, raw) {
+			return errors.New("签名不匹配")
+		}
+		return nil
+	}
+	n := sm2Curve.N
+	if len(raw) != 64 {
+		return errors.New("sm2 签名应为64字节（r||s）")
+	}
+	r, s := new(big.Int).SetBytes(raw[:32]), new(big.Int).SetBytes(raw[32:])
+	if r.Sign() == 0 || r.Cmp(n) >= 0 || s.Sign() == 0 || s.Cmp(n) >= 0 {
+		return errors.New("签名不匹配")
+	}
+	t := new(big.Int).Add(r, s)
+	t.Mod(t, n)
+	if t.Sign() == 0 {
+		return errors.New("签名不匹配")
+	}
+	x1, y1 := sm2Curve.ScalarBaseMult(s.FillBytes(make([]byte, 32)))
+	x2, y2 := sm2Curve.ScalarMult(k.sm2X, k.sm2Y, t.FillBytes(make([]byte, 32)))
+	x, _ := sm2Curve.Add(x1, y1, x2, y2)
+	e := new(big.Int).SetBytes(sm2Digest(k, msg))
+	e.Add(e, x)
+	e.Mod(e, n)
+	if e.Cmp(r) != 0 {
+		return errors.New("签名不匹配")
+	}
+	return nil
+}
+
+// sm2Digest is SM3(ZA || msg), ZA binding the signer ID and public key.
+func sm2Digest(pub *VerifyKey, msg []byte) []byte {
+	za := newSM3()
+	bitLen := len(sm2DefaultID) * 8
+	za.Write([]byte{byte(bitLen >> 8), byte(bitLen)})
+	za.Write(sm2DefaultID)
+	a := new(big.Int).Sub(sm2Curve.P, big.NewInt(3))
+	for _, v := range []*big.Int{a, sm2Curve.B, sm2Curve.Gx, sm2Curve.Gy, pub.sm2X, pub.sm2Y} {
+		za.Write(v.FillBytes(make([]byte, 32)))
+	}
+	h := newSM3()
+	h.Write(za.Sum(nil))
+	h.Write(msg)
+	return h.Sum(nil)
+}
+
+// sm2RandScalar returns a uniform value in [1, max-1].
+func sm2RandScalar(r io.Reader, max *big.Int) (*big.Int, error) {
+	for {
+		k, err := rand.Int(r, max)
+		if err != nil {
+			return nil, err
+		}
+		if k.Sign() > 0 {
+			return k, nil
+		}
+	}
+}
+
+// canonicalJSON re-encodes a JSON document with sorted keys and no
+// insignificant whitespace; numbers keep their original text.
+func canonicalJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("JSON 之后存在多余内容")
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// resolveSignKey returns the key from --sign-key, else the embedded one,
+// else nil.
+func resolveSignKey(path string) (*SignKey, error) {
+	if path != "" {
+		return loadSignKey(path)
+	}
+	if embeddedSignKey != "" {
+		key, err := parseSignKey(embeddedSignKey)
+		if err != nil {
+			return nil, fmt.Errorf("内置签名密钥无效: %v", err)
+		}
+		return key, nil
+	}
+	return nil, nil
+}
+
+func signResultFile(path string, key *SignKey) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	canon, err := canonicalJSON(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	sig, err := key.Sign(canon)
+	if err != nil {
+		return err
+	}
+	data, err = json.MarshalIndent(sig, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path+sigSuffix, append(data, '\n'), 0644)
+}
+
+// verifyResultFile checks path against sigPath with whichever of keys has
+// the signature's key ID, returning that key's ID.
+func verifyResultFile(path, sigPath string, keys []*VerifyKey) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var sig SignatureFile
+	if err := readJSONFile(sigPath, &sig); err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("未找到签名文件 %s", sigPath)
+		}
+		return "", fmt.Errorf("%s: %v", sigPath, err)
+	}
+	canon, err := canonicalJSON(data)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", path, err)
+	}
+	for _, key := range keys {
+		if key.KeyID() == sig.KeyID {
+			return sig.KeyID, key.Verify(canon, sig)
+		}
+	}
+	return "", fmt.Errorf("签名密钥 %s 不在已知公钥中", sig.KeyID)
+}
+
+func writeKeyPair(prefix string, key *SignKey) ([]string, error) {
+	privPath, pubPath := prefix+".key", prefix+".pub"
+	for _, path := range []string{privPath, pubPath} {
+		if _, err := os.Stat(path); err == nil {
+			return nil, fmt.Errorf("%s 已存在，不覆盖", path)
+		}
+	}
+	if err := os.WriteFile(privPath, []byte(key.String()+"\n"), 0600); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(pubPath, []byte(key.Public().String()+"\n"), 0644); err != nil {
+		return []string{privPath}, err
+	}
+	return []string{privPath, pubPath}, nil
+}
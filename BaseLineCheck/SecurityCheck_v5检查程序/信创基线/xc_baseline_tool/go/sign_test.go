@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/base64"
+	"math/big"
+	"testing"
+)
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	msg := []byte(`{"items":[],"os":"kylin"}`)
+	for _, alg := range []string{algSM2, algEd25519} {
+		t.Run(alg, func(t *testing.T) {
+			key, err := generateSignKey(alg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sig, err := key.Sign(msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sig.Algorithm != sigAlgorithm(alg) || sig.KeyID != key.Public().KeyID() {
+				t.Errorf("signature header = %s/%s", sig.Algorithm, sig.KeyID)
+			}
+			// Keys survive their one-line text form.
+			signKey, err := parseSignKey(key.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			pub, err := parseVerifyKey(signKey.Public().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := pub.Verify(msg, sig); err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+
+			tampered := append([]byte(nil), msg...)
+			tampered[len(tampered)-3] ^= 1
+			if pub.Verify(tampered, sig) == nil {
+				t.Error("tampered message verified")
+			}
+			raw, _ := base64.StdEncoding.DecodeString(sig.Signature)
+			raw[len(raw)-1] ^= 1
+			bad := sig
+			bad.Signature = base64.StdEncoding.EncodeToString(raw)
+			if pub.Verify(msg, bad) == nil {
+				t.Error("tampered signature verified")
+			}
+			other, _ := generateSignKey(alg)
+			if other.Public().Verify(msg, sig) == nil {
+				t.Error("signature verified with another key")
+			}
+		})
+	}
+}
+
+func TestSM2SignWithFixedNonce(t *testing.T) {
+	key, err := parseSignKey("sm2:3945208f7b2144b13f36e38ac6d39f95889393692860b51a42fb81ef4df7c5b8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := key.Public()
+	msg := []byte("message digest")
+	e := new(big.Int).SetBytes(sm2Digest(pub, msg))
+	inv := new(big.Int).ModInverse(new(big.Int).Add(key.sm2D, big.NewInt(1)), sm2Curve.N)
+	kk, _ := new(big.Int).SetString("59276e27d506861a16680f3ad9c02dccef3cc1fa3cdbe4ce6d54b80deac1bc21", 16)
+	r, s := sm2SignWith(key.sm2D, inv, e, kk)
+	if r == nil {
+		t.Fatal("nonce rejected")
+	}
+	raw := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	sig := SignatureFile{Algorithm: "sm2-sm3", Signature: base64.StdEncoding.EncodeToString(raw)}
+	if err := pub.Verify(msg, sig); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if pub.Verify([]byte("message digesT"), sig) == nil {
+		t.Error("tampered message verified")
+	}
+}
+
+func TestVerifyRejectsMalformed(t *testing.T) {
+	key, _ := generateSignKey(algSM2)
+	pub := key.Public()
+	msg := []byte("{}")
+	zero := base64.StdEncoding.EncodeToString(make([]byte, 64))
+	for name, sig := range map[string]SignatureFile{
+		"algorithm": {Algorithm: "ed25519", Signature: zero},
+		"base64":    {Algorithm: "sm2-sm3", Signature: "!"},
+		"length":    {Algorithm: "sm2-sm3", Signature: base64.StdEncoding.EncodeToString(make([]byte, 63))},
+		"zero r s":  {Algorithm: "sm2-sm3", Signature: zero},
+	} {
+		if pub.Verify(msg, sig) == nil {
+			t.Errorf("%s: malformed signature verified", name)
+		}
+	}
+}
+
+func TestParseKeyErrors(t *testing.T) {
+	for _, text := range []string{
+		"rsa:00",
+		"sm2:zz",
+		"sm2:" + "00",
+		"sm2:" + "0000000000000000000000000000000000000000000000000000000000000000",
+		"ed25519:0011",
+	} {
+		if _, err := parseSignKey(text); err == nil {
+			t.Errorf("parseSignKey(%q) succeeded", text)
+		}
+	}
+	// A point off the curve must not load as a public key.
+	off := "sm2:04" + "0000000000000000000000000000000000000000000000000000000000000001" + "0000000000000000000000000000000000000000000000000000000000000001"
+	if _, err := parseVerifyKey(off); err == nil {
+		t.Error("off-curve sm2 public key accepted")
+	}
+}
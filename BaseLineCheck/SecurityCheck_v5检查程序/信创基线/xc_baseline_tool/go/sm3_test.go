@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// Known-answer vectors from GB/T 32905-2016 appendix A.
+var sm3Vectors = []struct {
+	name, msg, sum string
+}{
+	{"abc", "abc", "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"},
+	{"512-bit", strings.Repeat("abcd", 16), "debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"},
+}
+
+func TestSM3KnownAnswer(t *testing.T) {
+	for _, v := range sm3Vectors {
+		if got := hex.EncodeToString(sm3Sum([]byte(v.msg))); got != v.sum {
+			t.Errorf("sm3Sum(%s) = %s, want %s", v.name, got, v.sum)
+		}
+	}
+}
+
+func TestSM3Streaming(t *testing.T) {
+	for _, v := range sm3Vectors {
+		h := newSM3()
+		for i := 0; i < len(v.msg); i += 7 {
+			end := i + 7
+			if end > len(v.msg) {
+				end = len(v.msg)
+			}
+			h.Write([]byte(v.msg[i:end]))
+		}
+		if got := hex.EncodeToString(h.Sum(nil)); got != v.sum {
+			t.Errorf("%s written in chunks = %s, want %s", v.name, got, v.sum)
+		}
+		// Sum must not change the running state.
+		if got := hex.EncodeToString(h.Sum(nil)); got != v.sum {
+			t.Errorf("%s second Sum = %s, want %s", v.name, got, v.sum)
+		}
+		h.Reset()
+		h.Write([]byte(v.msg))
+		if got := hex.EncodeToString(h.Sum(nil)); got != v.sum {
+			t.Errorf("%s after Reset = %s, want %s", v.name, got, v.sum)
+		}
+	}
+	if h := newSM3(); h.Size() != 32 || h.BlockSize() != 64 {
+		t.Errorf("Size/BlockSize = %d/%d, want 32/64", h.Size(), h.BlockSize())
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// System is everything a check may observe about the target host. Checks
+// must go through the RunContext helpers below rather than calling os or
+// exec directly, so the same check can run against a live host or an
+// offline root filesystem.
+type System interface {
+	ReadFile(path string) ([]byte, error)
+	Stat(path string) (os.FileInfo, error)
+	ReadDir(path string) ([]os.DirEntry, error)
+	LookPath(name string) (string, error)
+	Run(ctx context.Context, name string, args ...string) (string, int)
+	// Live reports whether commands and /proc reflect the target host.
+	Live() bool
+}
+
+var errOffline = errors.New("离线扫描模式不执行命令")
+
+// hostSystem reads files below root. With root "/" it is the live host;
+// any other root is treated as an offline image and never runs commands.
+type hostSystem struct {
+	root string
+}
+
+func newHostSystem(root string) hostSystem {
+	if root == "" {
+		root = "/"
+	}
+	return hostSystem{root: filepath.Clean(root)}
+}
+
+func (h hostSystem) Live() bool {
+	return h.root == "/"
+}
+
+func (h hostSystem) path(p string) (string, error) {
+	if h.Live() {
+		return p, nil
+	}
+	return resolveInRoot(h.root, p)
+}
+
+func (h hostSystem) ReadFile(p string) ([]byte, error) {
+	resolved, err := h.path(p)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(resolved)
+}
+
+func (h hostSystem) Stat(p string) (os.FileInfo, error) {
+	resolved, err := h.path(p)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(resolved)
+}
+
+func (h hostSystem) ReadDir(p string) ([]os.DirEntry, error) {
+	resolved, err := h.path(p)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadDir(resolved)
+}
+
+func (h hostSystem) LookPath(name string) (string, error) {
+	if !h.Live() {
+		return "", errOffline
+	}
+	return exec.LookPath(name)
+}
+
+func (h hostSystem) Run(ctx context.Context, name string, args ...string) (string, int) {
+	if !h.Live() {
+		return "", 127
+	}
+	return runCommand(ctx, name, args...)
+}
+
+// resolveInRoot maps an absolute path inside the image to a host path,
+// following symlinks relative to root so absolute links such as
+// /etc/os-release -> /usr/lib/os-release never escape to the host.
+func resolveInRoot(root, p string) (string, error) {
+	pending := strings.Split(strings.Trim(filepath.ToSlash(p), "/"), "/")
+	resolved := []string{}
+	hops := 0
+	for len(pending) > 0 {
+		part := pending[0]
+		pending = pending[1:]
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) > 0 {
+				resolved = resolved[:len(resolved)-1]
+			}
+			continue
+		}
+		candidate := filepath.Join(append([]string{root}, append(resolved, part)...)...)
+		info, err := os.Lstat(candidate)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			resolved = append(resolved, part)
+			continue
+		}
+		hops++
+		if hops > 40 {
+			return "", &os.PathError{Op: "resolve", Path: p, Err: errors.New("符号链接层级过多")}
+		}
+		target, err := os.Readlink(candidate)
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(target, "/") {
+			resolved = resolved[:0]
+		}
+		pending = append(strings.Split(strings.Trim(filepath.ToSlash(target), "/"), "/"), pending...)
+	}
+	return filepath.Join(append([]string{root}, resolved...)...), nil
+}
+
+// scanRoot returns the offline root directory, or "" for a live scan.
+func (rc *RunContext) scanRoot() string {
+	switch sys := rc.Sys.(type) {
+	case hostSystem:
+		if !sys.Live() {
+			return sys.root
+		}
+	case *recordingSystem:
+		inner := *rc
+		inner.Sys = sys.inner
+		return inner.scanRoot()
+	case *replaySystem:
+		return sys.manifest.Root
+	}
+	return ""
+}
+
+func (rc *RunContext) readFile(path string) string {
+	data, err := rc.Sys.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func (rc *RunContext) readLines(path string) []string {
+	data, err := rc.Sys.ReadFile(path)
+	if err != nil {
+		return []string{}
+	}
+	return splitFileLines(string(data))
+}
+
+func (rc *RunContext) fileExists(path string) bool {
+	_, err := rc.Sys.Stat(path)
+	return err == nil
+}
+
+func (rc *RunContext) firstExistingFile(paths []string) string {
+	for _, p := range paths {
+		if rc.fileExists(p) {
+			return p
+		}
+	}
+	return ""
+}
+
+func (rc *RunContext) commandExists(name string) bool {
+	_, err := rc.Sys.LookPath(name)
+	return err == nil
+}
+
+func (rc *RunContext) runCommand(name string, args ...string) (string, int) {
+	return rc.Sys.Run(rc.context(), name, args...)
+}
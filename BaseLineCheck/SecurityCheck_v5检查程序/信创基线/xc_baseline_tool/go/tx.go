@@ -0,0 +1,403 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Every remediation run gets a journal directory:
+//
+//	journal.json   files touched (with original mode/owner/hash), commands run
+//	backups/<n>    byte-exact copies of the files before the first change
+//
+// Files are backed up and the journal flushed before each write, so a run
+// interrupted half-way can still be rolled back with --rollback RUN_ID.
+var runsDir = "/var/lib/xc-baseline/runs"
+
+var runIDPattern = regexp.MustCompile(`^[0-9A-Za-z][0-9A-Za-z-]*$`)
+
+type txJournal struct {
+	RunID      string            `json:"run_id"`
+	Created    string            `json:"created"`
+	Files      []txFileRecord    `json:"files"`
+	Commands   []txCommandRecord `json:"commands"`
+	RolledBack string            `json:"rolled_back,omitempty"`
+}
+
+type txFileRecord struct {
+	Item        string      `json:"item"`
+	Path        string      `json:"path"`
+	Existed     bool        `json:"existed"`
+	Mode        fs.FileMode `json:"mode,omitempty"`
+	UID         int         `json:"uid"`
+	GID         int         `json:"gid"`
+	Backup      string      `json:"backup,omitempty"`
+	SHA256      string      `json:"sha256,omitempty"`
+	CreatedDirs []string    `json:"created_dirs,omitempty"`
+}
+
+type txCommandRecord struct {
+	Item     string   `json:"item"`
+	Argv     []string `json:"argv"`
+	ExitCode int      `json:"exit_code"`
+}
+
+// PlannedChange is one write or command collected by a dry run.
+type PlannedChange struct {
+	Item    string   `json:"item"`
+	Kind    string   `json:"kind"` // file, proc or command
+	Path    string   `json:"path,omitempty"`
+	Existed bool     `json:"existed,omitempty"`
+	Old     string   `json:"old,omitempty"`
+	New     string   `json:"new,omitempty"`
+	Argv    []string `json:"argv,omitempty"`
+}
+
+// Tx collects the side effects of one remediation run. In dry-run mode
+// nothing touches the host: writes land in an overlay that later reads in
+// the same run observe, and commands are only recorded.
+type Tx struct {
+	ID     string
+	Dir    string
+	DryRun bool
+
+	mu      sync.Mutex
+	item    string
+	journal *txJournal
+	touched map[string]bool
+	overlay map[string][]byte
+	Plan    []PlannedChange
+	// journalErr is set once the journal could not be flushed. Changes
+	// after that cannot be rolled back, so the run stops.
+	journalErr error
+}
+
+func newTx(dryRun bool) (*Tx, error) {
+	id, err := newRunID()
+	if err != nil {
+		return nil, err
+	}
+	tx := &Tx{
+		ID:      id,
+		Dir:     filepath.Join(runsDir, id),
+		DryRun:  dryRun,
+		journal: &txJournal{RunID: id, Created: time.Now().Format(time.RFC3339), Files: []txFileRecord{}, Commands: []txCommandRecord{}},
+		touched: map[string]bool{},
+		overlay: map[string][]byte{},
+		Plan:    []PlannedChange{},
+	}
+	if dryRun {
+		return tx, nil
+	}
+	if err := os.MkdirAll(filepath.Join(tx.Dir, "backups"), 0700); err != nil {
+		return nil, err
+	}
+	return tx, tx.flush()
+}
+
+func newRunID() (string, error) {
+	buf := make([]byte, 3)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(buf), nil
+}
+
+// begin attributes the following changes to item.
+func (tx *Tx) begin(item string) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.item = item
+}
+
+func (tx *Tx) flush() error {
+	if err := writeJSONFile(filepath.Join(tx.Dir, "journal.json"), tx.journal); err != nil {
+		tx.journalErr = fmt.Errorf("写入事务日志失败: %v", err)
+		return tx.journalErr
+	}
+	return nil
+}
+
+// failed reports a journal flush failure from any earlier change.
+func (tx *Tx) failed() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	return tx.journalErr
+}
+
+func (tx *Tx) writeFile(sys System, path string, data []byte, perm fs.FileMode) error {
+	if tx.DryRun {
+		return tx.planWrite(sys, path, data)
+	}
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	// Back up once per item and path, so rolling back one item restores
+	// the file as it was before that item, keeping earlier items' changes.
+	key := tx.item + "\x00" + path
+	if !tx.touched[key] {
+		rec, err := tx.backup(path)
+		if err != nil {
+			return fmt.Errorf("备份 %s 失败: %v", path, err)
+		}
+		if rec.CreatedDirs, err = mkdirRecorded(filepath.Dir(path)); err != nil {
+			return err
+		}
+		tx.journal.Files = append(tx.journal.Files, rec)
+		tx.touched[key] = true
+		if err := tx.flush(); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, data, perm)
+}
+
+// planWrite reads the previous content through sys, which in a dry run
+// already reflects earlier planned writes.
+func (tx *Tx) planWrite(sys System, path string, data []byte) error {
+	old, err := sys.ReadFile(path)
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.Plan = append(tx.Plan, PlannedChange{Item: tx.item, Kind: "file", Path: path, Existed: err == nil, Old: string(old), New: string(data)})
+	tx.overlay[path] = append([]byte(nil), data...)
+	return nil
+}
+
+func (tx *Tx) backup(path string) (txFileRecord, error) {
+	rec := txFileRecord{Item: tx.item, Path: path}
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return rec, nil
+	}
+	if err != nil {
+		return rec, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rec, err
+	}
+	rec.Existed = true
+	rec.Mode = info.Mode().Perm()
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		rec.UID, rec.GID = int(st.Uid), int(st.Gid)
+	}
+	rec.Backup = fmt.Sprintf("%d", len(tx.journal.Files))
+	rec.SHA256 = sha256Hex(data)
+	return rec, os.WriteFile(filepath.Join(tx.Dir, "backups", rec.Backup), data, 0600)
+}
+
+// mkdirRecorded creates dir and returns the directories it had to create,
+// outermost first, so rollback can remove them again.
+func mkdirRecorded(dir string) ([]string, error) {
+	missing := []string{}
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		}
+		missing = append([]string{d}, missing...)
+		if d == filepath.Dir(d) {
+			break
+		}
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+	return missing, os.MkdirAll(dir, 0755)
+}
+
+// writeProc changes runtime kernel state. It cannot be backed up; the
+// persistent setting written alongside it is what rollback restores.
+func (tx *Tx) writeProc(path, value string) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.DryRun {
+		tx.Plan = append(tx.Plan, PlannedChange{Item: tx.item, Kind: "proc", Path: path, New: value})
+		tx.overlay[path] = []byte(value)
+		return nil
+	}
+	if err := os.WriteFile(path, []byte(value), 0644); err != nil {
+		return fmt.Errorf("写入 %s 失败: %v", path, err)
+	}
+	tx.journal.Commands = append(tx.journal.Commands, txCommandRecord{Item: tx.item, Argv: []string{"write", path, value}})
+	return tx.flush()
+}
+
+func (tx *Tx) run(rc *RunContext, name string, args ...string) (string, int) {
+	argv := append([]string{name}, args...)
+	if tx.DryRun {
+		tx.mu.Lock()
+		tx.Plan = append(tx.Plan, PlannedChange{Item: tx.item, Kind: "command", Argv: argv})
+		tx.mu.Unlock()
+		return "", 0
+	}
+	out, code := rc.Sys.Run(rc.context(), name, args...)
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.journal.Commands = append(tx.journal.Commands, txCommandRecord{Item: tx.item, Argv: argv, ExitCode: code})
+	// A flush error is kept in journalErr; applyFix fails the item on it.
+	_ = tx.flush()
+	return out, code
+}
+
+// rollbackItem restores the files changed for one item, newest first.
+// Commands already run (service stops, firewall rules) are not undone.
+func (tx *Tx) rollbackItem(item string) ([]string, error) {
+	if tx.DryRun {
+		return nil, nil
+	}
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	return restoreFiles(tx.Dir, tx.journal.Files, func(rec txFileRecord) bool { return rec.Item == item })
+}
+
+func (tx *Tx) commandsFor(item string) int {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	count := 0
+	for _, cmd := range tx.journal.Commands {
+		if cmd.Item == item {
+			count++
+		}
+	}
+	return count
+}
+
+// rollbackRun restores every file recorded in a run's journal.
+func rollbackRun(id string) ([]string, error) {
+	if !runIDPattern.MatchString(id) {
+		return nil, fmt.Errorf("无效的运行ID: %s", id)
+	}
+	dir := filepath.Join(runsDir, id)
+	journal := &txJournal{}
+	if err := readJSONFile(filepath.Join(dir, "journal.json"), journal); err != nil {
+		return nil, err
+	}
+	if journal.RolledBack != "" {
+		return nil, fmt.Errorf("运行 %s 已于 %s 回滚", id, journal.RolledBack)
+	}
+	restored, err := restoreFiles(dir, journal.Files, func(txFileRecord) bool { return true })
+	if err != nil {
+		return restored, err
+	}
+	journal.RolledBack = time.Now().Format(time.RFC3339)
+	return restored, writeJSONFile(filepath.Join(dir, "journal.json"), journal)
+}
+
+func restoreFiles(dir string, files []txFileRecord, match func(txFileRecord) bool) ([]string, error) {
+	restored := []string{}
+	for i := len(files) - 1; i >= 0; i-- {
+		rec := files[i]
+		if !match(rec) {
+			continue
+		}
+		if err := restoreFile(dir, rec); err != nil {
+			return restored, fmt.Errorf("恢复 %s 失败: %v", rec.Path, err)
+		}
+		restored = append(restored, rec.Path)
+	}
+	return restored, nil
+}
+
+func restoreFile(dir string, rec txFileRecord) error {
+	if !rec.Existed {
+		if err := os.Remove(rec.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		for i := len(rec.CreatedDirs) - 1; i >= 0; i-- {
+			_ = os.Remove(rec.CreatedDirs[i])
+		}
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "backups", rec.Backup))
+	if err != nil {
+		return err
+	}
+	if sha256Hex(data) != rec.SHA256 {
+		return errors.New("备份文件校验失败")
+	}
+	if err := os.WriteFile(rec.Path, data, rec.Mode); err != nil {
+		return err
+	}
+	if err := os.Chmod(rec.Path, rec.Mode); err != nil {
+		return err
+	}
+	if err := os.Lchown(rec.Path, rec.UID, rec.GID); err != nil && !errors.Is(err, fs.ErrPermission) {
+		return err
+	}
+	current, err := os.ReadFile(rec.Path)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(current, data) {
+		return errors.New("恢复后内容不一致")
+	}
+	return nil
+}
+
+// txSystem lets a dry run read back the content it planned to write.
+type txSystem struct {
+	System
+	tx *Tx
+}
+
+func (s txSystem) pending(p string) ([]byte, bool) {
+	s.tx.mu.Lock()
+	defer s.tx.mu.Unlock()
+	data, ok := s.tx.overlay[p]
+	return data, ok
+}
+
+func (s txSystem) ReadFile(p string) ([]byte, error) {
+	if data, ok := s.pending(p); ok {
+		return append([]byte(nil), data...), nil
+	}
+	return s.System.ReadFile(p)
+}
+
+func (s txSystem) Stat(p string) (os.FileInfo, error) {
+	if data, ok := s.pending(p); ok {
+		return recordedFileInfo{name: filepath.Base(p), rec: statRecord{Exists: true, Mode: 0644, Size: int64(len(data))}}, nil
+	}
+	return s.System.Stat(p)
+}
+
+func (rc *RunContext) dryRun() bool {
+	return rc.Tx != nil && rc.Tx.DryRun
+}
+
+// writeFile is the only way remediation may change a file.
+func (rc *RunContext) writeFile(path string, data []byte, perm fs.FileMode) error {
+	if rc.Tx == nil {
+		return errors.New("修复必须在事务中执行")
+	}
+	return rc.Tx.writeFile(rc.Sys, path, data, perm)
+}
+
+// mutate runs a command that changes the host; dry runs only record it.
+func (rc *RunContext) mutate(name string, args ...string) (string, int) {
+	if rc.Tx == nil {
+		return "", 127
+	}
+	return rc.Tx.run(rc, name, args...)
+}
+
+func (rc *RunContext) writeProcValue(path, value string) error {
+	if rc.Tx == nil {
+		return errors.New("修复必须在事务中执行")
+	}
+	return rc.Tx.writeProc(path, value)
+}
+
+func planCommandLine(argv []string) string {
+	return strings.Join(argv, " ")
+}
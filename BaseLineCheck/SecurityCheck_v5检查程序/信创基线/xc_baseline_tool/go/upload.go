@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Results are posted to the collection server (server.py /log) that the
+// Windows SecurityCheck_v5 reports to. append_to_excel turns each object of
+// a posted list into one ledger row and adds the IP and receive time, so
+// an upload is a flat list of rows, one per item, using the Windows
+// Item/Issue columns. Uploads that fail are queued in the fail cache and
+// sent again, oldest first, on the next --upload.
+const (
+	defaultUploadCache = "/var/lib/xc-baseline/upload_fail.json"
+	uploadQueueMax     = 30
+	uploadTimeout      = 15 * time.Second
+)
+
+type UploadRow struct {
+	Item      string  `json:"Item"`
+	Issue     string  `json:"Issue"`
+	ID        string  `json:"检查项"`
+	Status    string  `json:"状态"`
+	Severity  string  `json:"级别"`
+	Host      string  `json:"主机名"`
+	OS        string  `json:"系统"`
+	Profile   string  `json:"基线配置"`
+	Score     float64 `json:"得分"`
+	CheckedAt string  `json:"检查时间"`
+}
+
+func validateUploadURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("--upload 须为 http(s) 地址，如 http://172.16.1.20:8000/log: %s", raw)
+	}
+	return nil
+}
+
+// readHostname reads the target's hostname, so replayed or offline results
+// are not attributed to the machine running the tool.
+func readHostname(rc *RunContext) string {
+	if data, err := rc.Sys.ReadFile("/etc/hostname"); err == nil {
+		if name := strings.TrimSpace(string(data)); name != "" {
+			return name
+		}
+	}
+	if rc.Sys.Live() {
+		if name, err := os.Hostname(); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// uploadRows flattens a result for the ledger. Issue is only filled for
+// items that need attention, as the Windows client only reports problems.
+func uploadRows(payload Output, host string, checkedAt time.Time) []UploadRow {
+	rows := []UploadRow{}
+	for _, item := range payload.Items {
+		row := UploadRow{
+			Item:      item.Name,
+			ID:        item.ID,
+			Status:    item.Status,
+			Severity:  item.Severity,
+			Host:      host,
+			OS:        payload.OS,
+			Profile:   payload.Profile.Name,
+			Score:     payload.Summary.Score,
+			CheckedAt: checkedAt.Format("2006-01-02 15:04:05"),
+		}
+		switch item.Status {
+		case "pass", "info", "not_applicable":
+		default:
+			row.Issue = item.Current
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// uploadResult queues body behind any earlier failed uploads and posts the
+// queue in order, stopping at the first failure. It returns how many were
+// sent; the rest stay in cachePath. Beyond uploadQueueMax the oldest
+// entries are dropped.
+func uploadResult(target, cachePath string, body []byte) (int, error) {
+	queue := []json.RawMessage{}
+	if err := readJSONFile(cachePath, &queue); err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("%s: %v", cachePath, err)
+	}
+	queue = append(queue, body)
+	if len(queue) > uploadQueueMax {
+		queue = queue[len(queue)-uploadQueueMax:]
+	}
+	client := &http.Client{Timeout: uploadTimeout}
+	sent := 0
+	var sendErr error
+	for _, pending := range queue {
+		if sendErr = postLog(client, target, pending); sendErr != nil {
+			break
+		}
+		sent++
+	}
+	queue = queue[sent:]
+	if len(queue) == 0 {
+		if err := os.Remove(cachePath); err != nil && !os.IsNotExist(err) {
+			return sent, err
+		}
+		return sent, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return sent, err
+	}
+	if err := writeJSONFile(cachePath, queue); err != nil {
+		return sent, err
+	}
+	return sent, fmt.Errorf("%v（%d份结果已缓存到 %s，下次 --upload 时重试）", sendErr, len(queue), cachePath)
+}
+
+func postLog(client *http.Client, target string, body []byte) error {
+	resp, err := client.Post(target, "application/json; charset=utf-8", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("服务器返回 %s", resp.Status)
+	}
+	var reply struct {
+		Status string `json:"status"`
+	}
+	if json.Unmarshal(data, &reply) != nil || reply.Status != "success" {
+		return errors.New("服务器未确认接收: " + strings.TrimSpace(string(data)))
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const waiverDateLayout = "2006-01-02"
+
+// Waiver accepts the risk of a failing item, or of some of its findings,
+// until Expires (inclusive). Finding matches a finding key ("listen:22"),
+// its subject ("22/tcp", "22/tcp(in)") or a port ("22").
+type Waiver struct {
+	Item          string `json:"item"`
+	Finding       string `json:"finding,omitempty"`
+	Justification string `json:"justification"`
+	Approver      string `json:"approver"`
+	Expires       string `json:"expires"`
+
+	expires time.Time
+}
+
+// WaiverRef is reported on items a waiver applied to, or would have
+// applied to had it not expired.
+type WaiverRef struct {
+	Findings      []string `json:"findings,omitempty"`
+	Justification string   `json:"justification"`
+	Approver      string   `json:"approver"`
+	Expires       string   `json:"expires"`
+	Expired       bool     `json:"expired,omitempty"`
+}
+
+type waiverFile struct {
+	Waivers []Waiver `json:"waivers"`
+}
+
+func loadWaivers(path string) ([]Waiver, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	raw := data
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".yaml" || ext == ".yml" || (ext != ".json" && !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{"))) {
+		raw, err = yamlToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	var file waiverFile
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for i := range file.Waivers {
+		w := &file.Waivers[i]
+		if _, ok := findChecker(w.Item); !ok {
+			return nil, fmt.Errorf("%s: 第%d条豁免的检查项未知: %s", path, i+1, w.Item)
+		}
+		if strings.TrimSpace(w.Justification) == "" || strings.TrimSpace(w.Approver) == "" {
+			return nil, fmt.Errorf("%s: 第%d条豁免（%s）缺少 justification 或 approver", path, i+1, w.Item)
+		}
+		expires, err := time.ParseInLocation(waiverDateLayout, w.Expires, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("%s: 第%d条豁免（%s）的 expires 须为 YYYY-MM-DD: %s", path, i+1, w.Item, w.Expires)
+		}
+		w.expires = expires
+	}
+	return file.Waivers, nil
+}
+
+func (w Waiver) expiredAt(now time.Time) bool {
+	return !now.Before(w.expires.AddDate(0, 0, 1))
+}
+
+func (w Waiver) ref(now time.Time) *WaiverRef {
+	return &WaiverRef{Justification: w.Justification, Approver: w.Approver, Expires: w.Expires, Expired: w.expiredAt(now)}
+}
+
+// matchesFinding reports whether the waiver's Finding covers key.
+// Listening checks are TCP only, so "22/tcp" also covers "listen:22".
+func (w Waiver) matchesFinding(key string) bool {
+	if key == w.Finding {
+		return true
+	}
+	kind, subject := "", key
+	if i := strings.Index(key, ":"); i >= 0 {
+		kind, subject = key[:i], key[i+1:]
+	}
+	if kind == "listen" && w.Finding == subject+"/tcp" {
+		return true
+	}
+	if i := strings.Index(subject, "("); i >= 0 && w.Finding == subject[:i] {
+		return true
+	}
+	return subject == w.Finding || strings.HasPrefix(subject, w.Finding+"/")
+}
+
+// applyWaivers turns failing items covered by an unexpired waiver into
+// waived. A finding-level waiver only waives the item once every failing
+// finding is covered. Expired waivers are reported but leave the item
+// failing.
+func applyWaivers(waivers []Waiver, items []OutputItem, now time.Time) []OutputItem {
+	for i := range items {
+		item := &items[i]
+		if item.Status != "fail" {
+			continue
+		}
+		for _, w := range waivers {
+			if w.Item != item.ID {
+				continue
+			}
+			if w.expiredAt(now) {
+				if item.Waiver == nil {
+					item.Waiver = w.ref(now)
+					item.Current += fmt.Sprintf("（豁免已于%s过期）", w.Expires)
+				}
+				continue
+			}
+			if w.Finding == "" {
+				item.Status = "waived"
+				item.Waiver = w.ref(now)
+				break
+			}
+			covered := []string{}
+			for j, f := range item.Findings {
+				if f.Status == "fail" && w.matchesFinding(f.Key) {
+					item.Findings[j].Status = "waived"
+					covered = append(covered, f.Key)
+				}
+			}
+			if len(covered) == 0 {
+				continue
+			}
+			if item.Waiver == nil || item.Waiver.Expired {
+				item.Waiver = w.ref(now)
+			}
+			item.Waiver.Findings = append(item.Waiver.Findings, covered...)
+			if len(failedFindings(item.Findings)) == 0 {
+				item.Status = "waived"
+				break
+			}
+		}
+	}
+	return items
+}
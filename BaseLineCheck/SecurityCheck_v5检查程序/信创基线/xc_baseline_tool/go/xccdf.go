@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+)
+
+// --format xccdf writes an XCCDF 1.2 benchmark listing the items that ran,
+// followed by one TestResult, the same shape as an `oscap xccdf eval
+// --results` file, so SCAP consoles can import it next to OpenSCAP scans.
+
+const (
+	xccdfNS        = "http://checklists.nist.gov/xccdf/1.2"
+	xccdfNamespace = "cn.xc-baseline"
+)
+
+var xccdfRuleID = regexp.MustCompile(`^xccdf_[^_\s]+_rule_\S+$`)
+
+// xccdfResults maps Result.Status to XCCDF rule-result values. Waived
+// items are reported as pass with an override from fail, which is how
+// XCCDF records an accepted deviation.
+var xccdfResults = map[string]string{
+	"pass":           "pass",
+	"fail":           "fail",
+	"waived":         "pass",
+	"manual":         "notchecked",
+	"info":           "informational",
+	"not_applicable": "notapplicable",
+	"timeout":        "error",
+	"error":          "error",
+}
+
+func xccdfResult(status string) string {
+	if r, ok := xccdfResults[status]; ok {
+		return r
+	}
+	return "unknown"
+}
+
+func (p *Profile) xccdfRule(id string) string {
+	if rule, ok := p.XCCDFRules[id]; ok {
+		return rule
+	}
+	return "xccdf_" + xccdfNamespace + "_rule_" + id
+}
+
+var xccdfUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+func xccdfID(kind, name string) string {
+	return "xccdf_" + xccdfNamespace + "_" + kind + "_" + xccdfUnsafe.ReplaceAllString(name, "_")
+}
+
+type xccdfBenchmark struct {
+	XMLName     xml.Name        `xml:"Benchmark"`
+	NS          string          `xml:"xmlns,attr"`
+	ID          string          `xml:"id,attr"`
+	Resolved    bool            `xml:"resolved,attr"`
+	Lang        string          `xml:"xml:lang,attr"`
+	Status      xccdfStatus     `xml:"status"`
+	Title       string          `xml:"title"`
+	Description string          `xml:"description"`
+	Version     string          `xml:"version"`
+	Profile     xccdfProfile    `xml:"Profile"`
+	Rules       []xccdfRule     `xml:"Rule"`
+	TestResult  xccdfTestResult `xml:"TestResult"`
+}
+
+type xccdfStatus struct {
+	Date  string `xml:"date,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xccdfProfile struct {
+	ID      string        `xml:"id,attr"`
+	Title   string        `xml:"title"`
+	Selects []xccdfSelect `xml:"select"`
+}
+
+type xccdfSelect struct {
+	IDRef    string `xml:"idref,attr"`
+	Selected bool   `xml:"selected,attr"`
+}
+
+type xccdfRule struct {
+	ID          string     `xml:"id,attr"`
+	Selected    bool       `xml:"selected,attr"`
+	Severity    string     `xml:"severity,attr"`
+	Weight      int        `xml:"weight,attr"`
+	Title       string     `xml:"title"`
+	Description string     `xml:"description,omitempty"`
+	Ident       xccdfIdent `xml:"ident"`
+}
+
+type xccdfIdent struct {
+	System string `xml:"system,attr"`
+	Value  string `xml:",chardata"`
+}
+
+type xccdfTestResult struct {
+	ID          string            `xml:"id,attr"`
+	EndTime     string            `xml:"end-time,attr"`
+	TestSystem  string            `xml:"test-system,attr"`
+	Benchmark   xccdfBenchmarkRef `xml:"benchmark"`
+	Title       string            `xml:"title"`
+	Profile     xccdfIDRef        `xml:"profile"`
+	Target      string            `xml:"target"`
+	Addresses   []string          `xml:"target-address"`
+	Facts       []xccdfFact       `xml:"target-facts>fact"`
+	RuleResults []xccdfRuleResult `xml:"rule-result"`
+	Scores      []xccdfScore      `xml:"score"`
+}
+
+type xccdfBenchmarkRef struct {
+	Href string `xml:"href,attr"`
+	ID   string `xml:"id,attr"`
+}
+
+type xccdfIDRef struct {
+	IDRef string `xml:"idref,attr"`
+}
+
+type xccdfFact struct {
+	Name  string `xml:"name,attr"`
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xccdfRuleResult struct {
+	IDRef    string         `xml:"idref,attr"`
+	Role     string         `xml:"role,attr"`
+	Time     string         `xml:"time,attr"`
+	Severity string         `xml:"severity,attr"`
+	Weight   int            `xml:"weight,attr"`
+	Result   string         `xml:"result"`
+	Override *xccdfOverride `xml:"override,omitempty"`
+	Ident    xccdfIdent     `xml:"ident"`
+	Messages []xccdfMessage `xml:"message"`
+}
+
+type xccdfOverride struct {
+	Time      string `xml:"time,attr"`
+	Authority string `xml:"authority,attr"`
+	OldResult string `xml:"old-result"`
+	NewResult string `xml:"new-result"`
+	Remark    string `xml:"remark"`
+}
+
+type xccdfMessage struct {
+	Severity string `xml:"severity,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type xccdfScore struct {
+	System  string `xml:"system,attr"`
+	Maximum string `xml:"maximum,attr"`
+	Value   string `xml:",chardata"`
+}
+
+func writeXCCDFReport(out io.Writer, profile *Profile, payload Output, host HostIdentity) error {
+	now := host.CheckedAt.Format(time.RFC3339)
+	benchmarkID := xccdfID("benchmark", "baseline")
+	profileID := xccdfID("profile", payload.Profile.Name)
+	target := valueOr(host.Hostname, "unknown")
+	if payload.Root != "" {
+		target = payload.Root
+	}
+	doc := xccdfBenchmark{
+		NS:          xccdfNS,
+		ID:          benchmarkID,
+		Resolved:    true,
+		Lang:        "zh-CN",
+		Status:      xccdfStatus{Date: host.CheckedAt.Format("2006-01-02"), Value: "accepted"},
+		Title:       "信创基线检查",
+		Description: "xc-baseline-go 检查项（基线配置 " + payload.Profile.Name + "）",
+		Version:     payload.Profile.Hash,
+		Profile:     xccdfProfile{ID: profileID, Title: payload.Profile.Name},
+		TestResult: xccdfTestResult{
+			ID:         xccdfID("testresult", payload.Profile.Name),
+			EndTime:    now,
+			TestSystem: "xc-baseline-go",
+			Benchmark:  xccdfBenchmarkRef{Href: "#" + benchmarkID, ID: benchmarkID},
+			Title:      "信创基线检查结果",
+			Profile:    xccdfIDRef{IDRef: profileID},
+			Target:     target,
+			Addresses:  host.Addresses,
+			Facts: []xccdfFact{
+				{Name: "urn:xccdf:fact:asset:identifier:host_name", Type: "string", Value: host.Hostname},
+				{Name: "urn:xc-baseline:fact:os", Type: "string", Value: payload.OS},
+			},
+		},
+	}
+	if host.Kernel != "" {
+		doc.TestResult.Facts = append(doc.TestResult.Facts, xccdfFact{Name: "urn:xc-baseline:fact:kernel", Type: "string", Value: host.Kernel})
+	}
+	for _, item := range payload.Items {
+		ruleID := profile.xccdfRule(item.ID)
+		weight := profile.weight(item.Severity)
+		ident := xccdfIdent{System: "urn:xc-baseline:item", Value: item.ID}
+		doc.Profile.Selects = append(doc.Profile.Selects, xccdfSelect{IDRef: ruleID, Selected: true})
+		doc.Rules = append(doc.Rules, xccdfRule{
+			ID: ruleID, Selected: true, Severity: item.Severity, Weight: weight,
+			Title: item.Name, Description: item.Desc, Ident: ident,
+		})
+		rr := xccdfRuleResult{
+			IDRef: ruleID, Role: "full", Time: now, Severity: item.Severity, Weight: weight,
+			Result: xccdfResult(item.Status), Ident: ident,
+			Messages: []xccdfMessage{{Severity: "info", Value: "当前: " + item.Current}},
+		}
+		if w := item.Waiver; w != nil && item.Status == "waived" {
+			rr.Override = &xccdfOverride{
+				Time: now, Authority: w.Approver, OldResult: "fail", NewResult: "pass",
+				Remark: fmt.Sprintf("已豁免: %s（有效期至 %s）", w.Justification, w.Expires),
+			}
+		}
+		for _, f := range failedFindings(item.Findings) {
+			rr.Messages = append(rr.Messages, xccdfMessage{Severity: "error", Value: findingLine(f)})
+		}
+		doc.TestResult.RuleResults = append(doc.TestResult.RuleResults, rr)
+	}
+	// Scored as in summarize: only pass and fail items carry weight.
+	sum := payload.Summary
+	doc.TestResult.Scores = []xccdfScore{
+		{System: "urn:xccdf:scoring:flat", Maximum: fmt.Sprintf("%d", sum.Total), Value: fmt.Sprintf("%d", sum.Earned)},
+		{System: "urn:xc-baseline:scoring:weighted", Maximum: "100", Value: fmt.Sprintf("%.1f", sum.Score)},
+	}
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
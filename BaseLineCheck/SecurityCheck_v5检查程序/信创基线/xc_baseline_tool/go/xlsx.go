@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// The XLSX export (检查台账) is a minimal SpreadsheetML package written with
+// archive/zip, like the DOCX report. The 检查结果 sheet has the columns of
+// the collection server's Monitor/summary.xlsx (IP and 时间, then the
+// --upload row), so rows copied from a USB stick line up with the ledger
+// the Windows SecurityCheck_v5 feeds. The 主机信息 sheet holds the host
+// identity and the score.
+
+var xlsxItemHeader = []string{"IP", "时间", "Item", "Issue", "检查项", "状态", "级别", "主机名", "系统", "基线配置", "得分", "检查时间"}
+
+var xlsxItemWidths = []float64{16, 20, 28, 48, 22, 10, 8, 18, 24, 14, 8, 20}
+
+// Excel refuses cells longer than this.
+const xlsxMaxCellChars = 32767
+
+// Cell styles, indexes into cellXfs of xlsxStyles.
+const (
+	xlsxStyleText   = 1
+	xlsxStyleHeader = 2
+)
+
+type xlsxCell struct {
+	text   string
+	number bool
+}
+
+func xlsxText(text string) xlsxCell { return xlsxCell{text: text} }
+func xlsxNumber(value float64) xlsxCell {
+	return xlsxCell{text: fmt.Sprintf("%g", value), number: true}
+}
+
+// xlsxColumn turns a 0-based column index into its letters: 0 is A, 26 AA.
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// xlsxSheet renders a worksheet whose first row is a frozen, filterable
+// header. Text goes in inline strings, so no shared string table is needed.
+func xlsxSheet(widths []float64, header []string, rows [][]xlsxCell) string {
+	var sb strings.Builder
+	sb.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	sb.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	sb.WriteString("<cols>")
+	for i, w := range widths {
+		sb.WriteString(fmt.Sprintf(`<col min="%d" max="%d" width="%g" customWidth="1"/>`, i+1, i+1, w))
+	}
+	sb.WriteString("</cols><sheetData>")
+	cells := []xlsxCell{}
+	for _, h := range header {
+		cells = append(cells, xlsxText(h))
+	}
+	all := append([][]xlsxCell{cells}, rows...)
+	for r, row := range all {
+		style := xlsxStyleText
+		if r == 0 {
+			style = xlsxStyleHeader
+		}
+		sb.WriteString(fmt.Sprintf(`<row r="%d">`, r+1))
+		for c, cell := range row {
+			ref := fmt.Sprintf("%s%d", xlsxColumn(c), r+1)
+			if cell.number {
+				sb.WriteString(fmt.Sprintf(`<c r="%s" s="%d"><v>%s</v></c>`, ref, style, cell.text))
+				continue
+			}
+			text := cell.text
+			if utf8.RuneCountInString(text) > xlsxMaxCellChars {
+				text = string([]rune(text)[:xlsxMaxCellChars])
+			}
+			sb.WriteString(fmt.Sprintf(`<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, docxEscape(text)))
+		}
+		sb.WriteString("</row>")
+	}
+	sb.WriteString("</sheetData>")
+	sb.WriteString(fmt.Sprintf(`<autoFilter ref="A1:%s%d"/>`, xlsxColumn(len(header)-1), len(all)))
+	sb.WriteString(`<pageMargins left="0.7" right="0.7" top="0.75" bottom="0.75" header="0.3" footer="0.3"/>`)
+	sb.WriteString("</worksheet>")
+	return sb.String()
+}
+
+func xlsxItemSheet(view ReportView) string {
+	host := view.Host
+	ip := ""
+	if len(host.Addresses) > 0 {
+		ip = host.Addresses[0]
+	}
+	checkedAt := host.CheckedAt.Format("2006-01-02 15:04:05")
+	rows := [][]xlsxCell{}
+	for _, r := range uploadRows(view.Output, host.Hostname, host.CheckedAt) {
+		rows = append(rows, []xlsxCell{
+			xlsxText(ip), xlsxText(checkedAt), xlsxText(r.Item), xlsxText(r.Issue), xlsxText(r.ID),
+			xlsxText(r.Status), xlsxText(r.Severity), xlsxText(r.Host), xlsxText(r.OS), xlsxText(r.Profile),
+			xlsxNumber(r.Score), xlsxText(r.CheckedAt),
+		})
+	}
+	return xlsxSheet(xlsxItemWidths, xlsxItemHeader, rows)
+}
+
+func xlsxHostSheet(view ReportView) string {
+	host := view.Host
+	sum := view.Output.Summary
+	rows := [][]xlsxCell{
+		{xlsxText("主机名"), xlsxText(host.Hostname)},
+		{xlsxText("IP地址"), xlsxText(strings.Join(host.Addresses, ", "))},
+		{xlsxText("操作系统"), xlsxText(host.OS)},
+		{xlsxText("内核版本"), xlsxText(host.Kernel)},
+	}
+	if host.Root != "" {
+		rows = append(rows, []xlsxCell{xlsxText("离线扫描"), xlsxText(host.Root)})
+	}
+	score := xlsxText("无可评分检查项")
+	if sum.Total > 0 {
+		score = xlsxNumber(sum.Score)
+	}
+	counts := []string{}
+	for _, c := range view.Counts {
+		counts = append(counts, fmt.Sprintf("%s %d", c.Label, c.Count))
+	}
+	rows = append(rows,
+		[]xlsxCell{xlsxText("基线配置"), xlsxText(view.Output.Profile.Name + " (" + view.Output.Profile.Hash + ")")},
+		[]xlsxCell{xlsxText("合规得分"), score},
+		[]xlsxCell{xlsxText("等级"), xlsxText(sum.Grade)},
+		[]xlsxCell{xlsxText("检查项数"), xlsxText(fmt.Sprintf("%d（%s）", len(view.Items), strings.Join(counts, "，")))},
+		[]xlsxCell{xlsxText("检查时间"), xlsxText(host.CheckedAt.Format("2006-01-02 15:04:05"))},
+		[]xlsxCell{xlsxText("检查人"), xlsxText(view.Inspector)},
+	)
+	return xlsxSheet([]float64{14, 60}, []string{"项目", "内容"}, rows)
+}
+
+const xlsxContentTypes = `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet2.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>` +
+	`</Types>`
+
+const xlsxRels = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbook = `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="检查结果" sheetId="1" r:id="rId1"/><sheet name="主机信息" sheetId="2" r:id="rId2"/></sheets>` +
+	`</workbook>`
+
+const xlsxWorkbookRels = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/>` +
+	`<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// Text in 宋体 11; header cells bold on grey, all cells bordered and wrapped.
+const xlsxStyles = `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="宋体"/><charset val="134"/></font>` +
+	`<font><b/><sz val="11"/><name val="宋体"/><charset val="134"/></font></fonts>` +
+	`<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill>` +
+	`<fill><patternFill patternType="solid"><fgColor rgb="FFF2F2F2"/><bgColor indexed="64"/></patternFill></fill></fills>` +
+	`<borders count="2"><border><left/><right/><top/><bottom/><diagonal/></border>` +
+	`<border><left style="thin"><color rgb="FF808080"/></left><right style="thin"><color rgb="FF808080"/></right>` +
+	`<top style="thin"><color rgb="FF808080"/></top><bottom style="thin"><color rgb="FF808080"/></bottom><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="1" xfId="0" applyBorder="1" applyAlignment="1"><alignment vertical="top" wrapText="1"/></xf>` +
+	`<xf numFmtId="0" fontId="1" fillId="2" borderId="1" xfId="0" applyFont="1" applyFill="1" applyBorder="1" applyAlignment="1"><alignment horizontal="center" vertical="center"/></xf></cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+func xlsxCore(view ReportView) string {
+	creator := valueOr(view.Inspector, "xc-baseline-go")
+	return `<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" ` +
+		`xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<dc:title>` + docxEscape("信创基线检查台账 - "+view.Host.Hostname) + `</dc:title>` +
+		`<dc:creator>` + docxEscape(creator) + `</dc:creator>` +
+		`<dcterms:created xsi:type="dcterms:W3CDTF">` + view.Host.CheckedAt.UTC().Format("2006-01-02T15:04:05Z") + `</dcterms:created>` +
+		`</cp:coreProperties>`
+}
+
+func writeXLSXReport(out io.Writer, view ReportView) error {
+	return writeOOXMLPackage(out, view.Host.CheckedAt, []ooxmlPart{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"docProps/core.xml", xlsxCore(view)},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+		{"xl/worksheets/sheet1.xml", xlsxItemSheet(view)},
+		{"xl/worksheets/sheet2.xml", xlsxHostSheet(view)},
+	})
+}
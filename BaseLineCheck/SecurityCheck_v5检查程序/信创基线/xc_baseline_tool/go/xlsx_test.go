@@ -0,0 +1,130 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestXLSXColumn(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 11: "L", 25: "Z", 26: "AA", 27: "AB", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if got := xlsxColumn(i); got != want {
+			t.Errorf("xlsxColumn(%d) = %s, want %s", i, got, want)
+		}
+	}
+}
+
+// xlsxRows reads the cell texts of a worksheet, row by row.
+func xlsxRows(t *testing.T, data []byte) [][]string {
+	t.Helper()
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Inline string `xml:"is>t"`
+				Value  string `xml:"v"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(data, &sheet); err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]string{}
+	for _, r := range sheet.Rows {
+		row := []string{}
+		for _, c := range r.Cells {
+			row = append(row, c.Inline+c.Value)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestWriteXLSXReport(t *testing.T) {
+	checkedAt := time.Date(2026, 5, 6, 9, 30, 0, 0, time.Local)
+	view := ReportView{
+		Inspector: "张三",
+		Host:      HostIdentity{Hostname: "uos-01", Addresses: []string{"10.0.0.5", "10.0.1.5"}, OS: "UOS 20", Kernel: "5.10.0", CheckedAt: checkedAt},
+		Output: Output{
+			OS:      "UOS 20",
+			Profile: ProfileRef{Name: "default", Hash: "abc123"},
+			Summary: Summary{Score: 87.5, Grade: "B", Earned: 7, Total: 8},
+			Items: []OutputItem{
+				{ID: "ssh_root_login", Name: "禁止root远程登录", Severity: "high", Status: "fail", Current: "PermitRootLogin yes"},
+				{ID: "ipv6", Name: "禁用IPv6", Severity: "low", Status: "pass", Current: "已禁用 <all>"},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	if err := writeXLSXReport(&buf, view); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		dec := xml.NewDecoder(bytes.NewReader(data))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: %v", f.Name, err)
+			}
+		}
+		parts[f.Name] = data
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		if parts[name] == nil {
+			t.Errorf("missing part %s", name)
+		}
+	}
+
+	items := xlsxRows(t, parts["xl/worksheets/sheet1.xml"])
+	want := [][]string{
+		xlsxItemHeader,
+		{"10.0.0.5", "2026-05-06 09:30:00", "禁止root远程登录", "PermitRootLogin yes", "ssh_root_login", "fail", "high", "uos-01", "UOS 20", "default", "87.5", "2026-05-06 09:30:00"},
+		{"10.0.0.5", "2026-05-06 09:30:00", "禁用IPv6", "", "ipv6", "pass", "low", "uos-01", "UOS 20", "default", "87.5", "2026-05-06 09:30:00"},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("item sheet\n got %q\nwant %q", items, want)
+	}
+	if !strings.Contains(string(parts["xl/worksheets/sheet1.xml"]), `<autoFilter ref="A1:L3"/>`) {
+		t.Error("item sheet has no filter over its rows")
+	}
+
+	host := map[string]string{}
+	for _, row := range xlsxRows(t, parts["xl/worksheets/sheet2.xml"])[1:] {
+		host[row[0]] = row[1]
+	}
+	for key, value := range map[string]string{"主机名": "uos-01", "IP地址": "10.0.0.5, 10.0.1.5", "内核版本": "5.10.0", "合规得分": "87.5", "检查人": "张三"} {
+		if host[key] != value {
+			t.Errorf("host sheet %s = %q, want %q", key, host[key], value)
+		}
+	}
+}
+
+func TestParseOutputTargetsXLSXNeedsFile(t *testing.T) {
+	if _, err := parseOutputTargets("xlsx", false, "", ""); err == nil {
+		t.Error("xlsx to stdout accepted")
+	}
+	targets, err := parseOutputTargets("json,xlsx", false, "out/result", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if targets[1].Path != "out/result.xlsx" {
+		t.Errorf("xlsx path = %s", targets[1].Path)
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Minimal YAML subset for profile-style files: block mappings, block
+// sequences (including sequences of mappings), flow lists/maps of scalars,
+// quoted and plain scalars, and # comments. Anchors, tags and block
+// scalars (| >) are rejected rather than guessed at.
+
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// yamlToJSON converts a YAML document to JSON so it can be decoded with
+// encoding/json into the same structs as the JSON form.
+func yamlToJSON(data []byte) ([]byte, error) {
+	value, err := parseYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+func parseYAML(data []byte) (interface{}, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if strings.Contains(raw, "\t") && strings.TrimLeft(raw, " \t") != strings.TrimLeft(raw, " ") {
+			return nil, fmt.Errorf("yaml 第%d行: 缩进不能使用Tab", i+1)
+		}
+		text := stripYAMLComment(raw)
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		indent := len(text) - len(strings.TrimLeft(text, " "))
+		p.lines = append(p.lines, yamlLine{num: i + 1, indent: indent, text: strings.TrimRight(trimmed, " ")})
+	}
+	if len(p.lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	value, err := p.parseBlock(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("yaml 第%d行: 缩进不一致", p.lines[p.pos].num)
+	}
+	return value, nil
+}
+
+func (p *yamlParser) parseBlock(indent int) (interface{}, error) {
+	line := p.lines[p.pos]
+	if isYAMLSeqItem(line.text) {
+		return p.parseSeq(indent)
+	}
+	return p.parseMap(indent)
+}
+
+func (p *yamlParser) parseSeq(indent int) (interface{}, error) {
+	list := []interface{}{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("yaml 第%d行: 缩进不一致", line.num)
+		}
+		if !isYAMLSeqItem(line.text) {
+			break
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+		if rest == "" {
+			p.pos++
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				value, err := p.parseBlock(p.lines[p.pos].indent)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+			} else {
+				list = append(list, nil)
+			}
+			continue
+		}
+		if _, _, ok := splitYAMLKey(rest); ok || isYAMLSeqItem(rest) {
+			// "- key: value" opens a nested block at the column after the dash.
+			offset := len(line.text) - len(rest)
+			p.lines[p.pos] = yamlLine{num: line.num, indent: indent + offset, text: rest}
+			value, err := p.parseBlock(indent + offset)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+			continue
+		}
+		value, err := parseYAMLScalarOrFlow(rest, line.num)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+		p.pos++
+	}
+	return list, nil
+}
+
+func (p *yamlParser) parseMap(indent int) (interface{}, error) {
+	m := map[string]interface{}{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("yaml 第%d行: 缩进不一致", line.num)
+		}
+		if isYAMLSeqItem(line.text) {
+			break
+		}
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("yaml 第%d行: 无法解析 %q", line.num, line.text)
+		}
+		if _, dup := m[key]; dup {
+			return nil, fmt.Errorf("yaml 第%d行: 重复的键 %q", line.num, key)
+		}
+		p.pos++
+		if rest != "" {
+			value, err := parseYAMLScalarOrFlow(rest, line.num)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+			continue
+		}
+		if p.pos < len(p.lines) {
+			next := p.lines[p.pos]
+			if next.indent > indent || (next.indent == indent && isYAMLSeqItem(next.text)) {
+				value, err := p.parseBlock(next.indent)
+				if err != nil {
+					return nil, err
+				}
+				m[key] = value
+				continue
+			}
+		}
+		m[key] = nil
+	}
+	return m, nil
+}
+
+func isYAMLSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitYAMLKey splits "key: value" at the first colon outside quotes that
+// is followed by a space or the end of line.
+func splitYAMLKey(text string) (string, string, bool) {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 {
+				quote = c
+			}
+		case c == '[' || c == '{':
+			if i == 0 {
+				return "", "", false
+			}
+		case c == ':':
+			if i+1 == len(text) || text[i+1] == ' ' {
+				key := strings.TrimSpace(text[:i])
+				if key == "" {
+					return "", "", false
+				}
+				if unq, err := unquoteYAML(key); err == nil {
+					key = unq
+				}
+				return key, strings.TrimSpace(text[i+1:]), true
+			}
+		}
+	}
+	return "", "", false
+}
+
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if c == '"' || c == '\'' {
+			quote = c
+			continue
+		}
+		if c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func parseYAMLScalarOrFlow(text string, num int) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("yaml 第%d行: 流式列表未闭合", num)
+		}
+		list := []interface{}{}
+		for _, part := range splitYAMLFlow(text[1 : len(text)-1]) {
+			value, err := parseYAMLScalar(part, num)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	case strings.HasPrefix(text, "{"):
+		if !strings.HasSuffix(text, "}") {
+			return nil, fmt.Errorf("yaml 第%d行: 流式映射未闭合", num)
+		}
+		m := map[string]interface{}{}
+		for _, part := range splitYAMLFlow(text[1 : len(text)-1]) {
+			key, rest, ok := splitYAMLKey(part)
+			if !ok {
+				return nil, fmt.Errorf("yaml 第%d行: 无法解析 %q", num, part)
+			}
+			value, err := parseYAMLScalar(rest, num)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return m, nil
+	case text == "|" || text == ">" || strings.HasPrefix(text, "|-") || strings.HasPrefix(text, ">-"):
+		return nil, fmt.Errorf("yaml 第%d行: 不支持多行文本块", num)
+	case strings.HasPrefix(text, "&") || strings.HasPrefix(text, "*") || strings.HasPrefix(text, "!"):
+		return nil, fmt.Errorf("yaml 第%d行: 不支持锚点/标签", num)
+	}
+	return parseYAMLScalar(text, num)
+}
+
+func splitYAMLFlow(text string) []string {
+	parts := []string{}
+	var quote byte
+	start := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if c == '"' || c == '\'' {
+			quote = c
+			continue
+		}
+		if c == ',' {
+			parts = append(parts, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(text[start:]); last != "" {
+		parts = append(parts, last)
+	}
+	return parts
+}
+
+func parseYAMLScalar(text string, num int) (interface{}, error) {
+	if strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'") {
+		value, err := unquoteYAML(text)
+		if err != nil {
+			return nil, fmt.Errorf("yaml 第%d行: %v", num, err)
+		}
+		return value, nil
+	}
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f, nil
+	}
+	return text, nil
+}
+
+func unquoteYAML(text string) (string, error) {
+	if len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' {
+		return strconv.Unquote(text)
+	}
+	if len(text) >= 2 && text[0] == '\'' && text[len(text)-1] == '\'' {
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+	if strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'") {
+		return "", fmt.Errorf("引号未闭合: %s", text)
+	}
+	return text, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestYAMLToJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"empty", "", `{}`},
+		{"document marker", "---\na: 1\n", `{"a":1}`},
+		{"scalars", "i: 10\nf: 1.5\nb: true\nn: ~\ns: plain text\n", `{"b":true,"f":1.5,"i":10,"n":null,"s":"plain text"}`},
+		{"nested maps", "password:\n  min_len: 10\n  lock:\n    deny: 5\n", `{"password":{"lock":{"deny":5},"min_len":10}}`},
+		{"block list", "ports:\n  - 22\n  - 23\n", `{"ports":[22,23]}`},
+		{"list at key indent", "ports:\n- 22\n- 23\nname: x\n", `{"name":"x","ports":[22,23]}`},
+		{"list of maps", "rules:\n  - id: a\n    weight: 1\n  - id: b\n", `{"rules":[{"id":"a","weight":1},{"id":"b"}]}`},
+		{"nested list", "m:\n  - - 1\n    - 2\n", `{"m":[[1,2]]}`},
+		{"dash then block", "l:\n  -\n    k: v\n", `{"l":[{"k":"v"}]}`},
+		{"flow list", "ports: [22, \"a,b\", 'c']\n", `{"ports":[22,"a,b","c"]}`},
+		{"flow map", "m: {a: 1, b: x}\n", `{"m":{"a":1,"b":"x"}}`},
+		{"double quoted", `s: "a: b # not a comment\t"` + "\n", `{"s":"a: b # not a comment\t"}`},
+		{"single quoted", "s: 'it''s'\n", `{"s":"it's"}`},
+		{"quoted key", "\"a b\": 1\n", `{"a b":1}`},
+		{"quoted number stays string", "s: \"10\"\n", `{"s":"10"}`},
+		{"comments", "# header\na: 1 # trailing\n  # indented\nb: x#y\n", `{"a":1,"b":"x#y"}`},
+		{"crlf", "a: 1\r\nb: 2\r\n", `{"a":1,"b":2}`},
+		{"empty value", "a:\nb: 1\n", `{"a":null,"b":1}`},
+		{"colon in value", "url: http://x/y\n", `{"url":"http://x/y"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := yamlToJSON([]byte(tt.yaml))
+			if err != nil {
+				t.Fatalf("yamlToJSON: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYAMLToJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"tab indent", "a:\n\tb: 1\n", "第2行: 缩进不能使用Tab"},
+		{"deeper sibling", "a: 1\n  b: 2\n", "第2行: 缩进不一致"},
+		{"deeper list item", "l:\n  - 1\n    - 2\n", "第3行: 缩进不一致"},
+		{"dedent below root", "  a: 1\nb: 2\n", "第2行: 缩进不一致"},
+		{"duplicate key", "a: 1\na: 2\n", "第2行: 重复的键"},
+		{"not a mapping", "a: 1\njust text\n", "第2行: 无法解析"},
+		{"unclosed flow list", "a: [1, 2\n", "第1行: 流式列表未闭合"},
+		{"unclosed flow map", "a: {b: 1\n", "第1行: 流式映射未闭合"},
+		{"unclosed quote", "a: \"x\n", "第1行: 引号未闭合"},
+		{"block scalar", "a: |\n  x\n", "第1行: 不支持多行文本块"},
+		{"anchor", "a: &x 1\n", "第1行: 不支持锚点/标签"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := yamlToJSON([]byte(tt.yaml))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}